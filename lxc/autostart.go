@@ -0,0 +1,151 @@
+package lxc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/jtopjian/terraform-provider-lxc/lxcconfig"
+)
+
+func lxcAutostartSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"enabled": &schema.Schema{
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  true,
+				},
+				"order": &schema.Schema{
+					Type:     schema.TypeInt,
+					Optional: true,
+					Default:  0,
+				},
+				"delay": &schema.Schema{
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      0,
+					ValidateFunc: lxcValidateAutostartDelay,
+				},
+				"groups": &schema.Schema{
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem: &schema.Schema{
+						Type:         schema.TypeString,
+						ValidateFunc: lxcValidateAutostartGroup,
+					},
+				},
+			},
+		},
+	}
+}
+
+// lxcIsAutostartKey reports whether a config key is managed by the
+// autostart block.
+func lxcIsAutostartKey(k string) bool {
+	switch k {
+	case "lxc.start.auto", "lxc.start.order", "lxc.start.delay", "lxc.group":
+		return true
+	}
+
+	return false
+}
+
+// lxcAutostartConfig adds the config entries of an autostart block to a
+// config file, as a section of their own. lxc.group is repeated for every
+// group.
+func lxcAutostartConfig(a []interface{}, f *lxcconfig.File) {
+	if len(a) == 0 || a[0] == nil {
+		return
+	}
+
+	autostart := a[0].(map[string]interface{})
+	f.AddComment(lxcAutostartSection)
+	if autostart["enabled"].(bool) {
+		f.Add("lxc.start.auto", "1")
+	} else {
+		f.Add("lxc.start.auto", "0")
+	}
+	f.Add("lxc.start.order", strconv.Itoa(autostart["order"].(int)))
+	f.Add("lxc.start.delay", strconv.Itoa(autostart["delay"].(int)))
+
+	for _, group := range autostart["groups"].([]interface{}) {
+		f.Add("lxc.group", group.(string))
+	}
+}
+
+// lxcReadAutostart turns the autostart section of a config file back into
+// an autostart block. A section without entries has no block.
+func lxcReadAutostart(f *lxcconfig.File) ([]interface{}, error) {
+	autostart := map[string]interface{}{
+		"enabled": false,
+		"order":   0,
+		"delay":   0,
+		"groups":  []interface{}{},
+	}
+
+	found := false
+	for _, entry := range f.Entries() {
+		if !lxcIsAutostartKey(entry.Key) {
+			continue
+		}
+		found = true
+
+		switch entry.Key {
+		case "lxc.start.auto":
+			autostart["enabled"] = entry.Value == "1"
+		case "lxc.start.order", "lxc.start.delay":
+			v, err := strconv.Atoi(entry.Value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid value for %s: %s", entry.Key, entry.Value)
+			}
+			autostart[strings.TrimPrefix(entry.Key, "lxc.start.")] = v
+		case "lxc.group":
+			autostart["groups"] = append(autostart["groups"].([]interface{}), entry.Value)
+		}
+	}
+
+	if !found {
+		return []interface{}{}, nil
+	}
+
+	return []interface{}{autostart}, nil
+}
+
+// lxcValidateAutostartOptions rejects options that set the same keys as
+// an autostart block.
+func lxcValidateAutostartOptions(d *schema.ResourceDiff, meta interface{}) error {
+	if len(d.Get("autostart").([]interface{})) == 0 {
+		return nil
+	}
+
+	for k := range d.Get("options").(map[string]interface{}) {
+		if lxcIsAutostartKey(k) {
+			return fmt.Errorf("%s can not be set in options together with an autostart block", k)
+		}
+	}
+
+	return nil
+}
+
+func lxcValidateAutostartDelay(v interface{}, k string) (ws []string, errors []error) {
+	if v.(int) < 0 {
+		errors = append(errors, fmt.Errorf("%s must not be negative", k))
+	}
+
+	return
+}
+
+func lxcValidateAutostartGroup(v interface{}, k string) (ws []string, errors []error) {
+	group := v.(string)
+	if group == "" || strings.ContainsAny(group, " \t,") {
+		errors = append(errors, fmt.Errorf("%s must be a group name without spaces or commas", k))
+	}
+
+	return
+}
@@ -0,0 +1,97 @@
+package lxc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jtopjian/terraform-provider-lxc/lxcconfig"
+)
+
+func TestLXCAutostartConfig(t *testing.T) {
+	autostart := []interface{}{
+		map[string]interface{}{
+			"enabled": true,
+			"order":   10,
+			"delay":   5,
+			"groups":  []interface{}{"onboot", "web"},
+		},
+	}
+
+	f := lxcconfig.New()
+	lxcAutostartConfig(autostart, f)
+
+	expected := `# autostart
+lxc.start.auto = 1
+lxc.start.order = 10
+lxc.start.delay = 5
+lxc.group = onboot
+lxc.group = web
+`
+	if actual := string(f.Bytes()); actual != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, actual)
+	}
+
+	actual, err := lxcReadAutostart(f)
+	if err != nil {
+		t.Fatalf("Unexpected error reading autostart: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual, autostart) {
+		t.Fatalf("Expected %#v, got %#v", autostart, actual)
+	}
+
+	actual, err = lxcReadAutostart(lxcconfig.New())
+	if err != nil || len(actual) != 0 {
+		t.Fatalf("Expected no autostart block, got %#v (%v)", actual, err)
+	}
+}
+
+func TestLXCValidateAutostartGroup(t *testing.T) {
+	for _, group := range []string{"onboot", "web-1"} {
+		if _, errs := lxcValidateAutostartGroup(group, "group"); len(errs) > 0 {
+			t.Fatalf("Unexpected errors for %s: %v", group, errs)
+		}
+	}
+
+	for _, group := range []string{"", "web servers", "web,db"} {
+		if _, errs := lxcValidateAutostartGroup(group, "group"); len(errs) == 0 {
+			t.Fatalf("Expected an error for %q", group)
+		}
+	}
+}
+
+func TestLXCConfigSections(t *testing.T) {
+	f, err := lxcconfig.Parse(strings.NewReader(`# Managed by Terraform. Changes to this file will be overwritten.
+lxc.start.auto = 1
+lxc.mount.entry = /srv srv none bind 0 0
+# limits
+lxc.cgroup.memory.limit_in_bytes = 512M
+# autostart
+lxc.start.auto = 0
+lxc.group = web
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sections := lxcConfigSections(f)
+
+	// autostart keys outside of the autostart section belong to options
+	if actual := sections[""].Get("lxc.start.auto"); !reflect.DeepEqual(actual, []string{"1"}) {
+		t.Fatalf("Unexpected options: %s", sections[""].Bytes())
+	}
+
+	if actual := sections[lxcLimitsSection].Get("lxc.cgroup.memory.limit_in_bytes"); !reflect.DeepEqual(actual, []string{"512M"}) {
+		t.Fatalf("Unexpected limits: %s", sections[lxcLimitsSection].Bytes())
+	}
+
+	autostart, err := lxcReadAutostart(sections[lxcAutostartSection])
+	if err != nil {
+		t.Fatalf("Unexpected error reading autostart: %s", err)
+	}
+
+	if autostart[0].(map[string]interface{})["enabled"] != false {
+		t.Fatalf("Unexpected autostart block: %#v", autostart)
+	}
+}
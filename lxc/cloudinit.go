@@ -0,0 +1,125 @@
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"gopkg.in/lxc/go-lxc.v2"
+)
+
+const (
+	lxcCloudInitSeedDir    = "/var/lib/cloud/seed/nocloud-net"
+	lxcCloudInitResultFile = "/var/lib/cloud/data/result.json"
+)
+
+// lxcWriteCloudInit writes the cloud-init attributes into the rootfs of a
+// stopped container as a NoCloud seed.
+func lxcWriteCloudInit(c *lxc.Container, d *schema.ResourceData) error {
+	userData := d.Get("user_data").(string)
+	metaData := d.Get("meta_data").(string)
+	networkConfig := d.Get("network_config").(string)
+
+	if userData == "" && metaData == "" && networkConfig == "" {
+		return nil
+	}
+
+	// the NoCloud datasource requires meta-data
+	if metaData == "" {
+		metaData = fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", c.Name(), c.Name())
+	}
+
+	rootfs, err := lxcRootfsPath(c)
+	if err != nil {
+		return err
+	}
+
+	idmap, err := lxcParseIDMap(c)
+	if err != nil {
+		return err
+	}
+
+	seed := map[string]string{
+		"user-data":      userData,
+		"meta-data":      metaData,
+		"network-config": networkConfig,
+	}
+
+	log.Printf("[INFO] Writing cloud-init seed for container %s", c.Name())
+	return lxcWriteCloudInitSeed(rootfs, idmap, seed)
+}
+
+// lxcWriteCloudInitSeed writes the files of a NoCloud seed into a rootfs.
+// Empty files are left out.
+func lxcWriteCloudInitSeed(rootfs string, idmap lxcIDMap, seed map[string]string) error {
+	for name, content := range seed {
+		if content == "" {
+			continue
+		}
+
+		if err := lxcWriteFile(rootfs, idmap, lxcCloudInitSeedDir+"/"+name, content, 0600, 0, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lxcWaitForCloudInit waits until cloud-init has finished booting the
+// container and fails if cloud-init reported any errors.
+func lxcWaitForCloudInit(c *lxc.Container, timeout time.Duration) error {
+	log.Printf("[INFO] Waiting for cloud-init to finish in container %s", c.Name())
+	args := []string{"cat", lxcCloudInitResultFile}
+
+	return resource.Retry(timeout, func() *resource.RetryError {
+		output, status, err := lxcRunCommand(c, args, lxc.DefaultAttachOptions)
+		if err != nil {
+			return resource.NonRetryableError(err)
+		}
+
+		// the result is only written once cloud-init has finished
+		if status != 0 {
+			return resource.RetryableError(fmt.Errorf("cloud-init has not finished in container %s", c.Name()))
+		}
+
+		if err := lxcCloudInitResult(output); err != nil {
+			return resource.NonRetryableError(fmt.Errorf("cloud-init failed in container %s: %s", c.Name(), err))
+		}
+
+		return nil
+	})
+}
+
+// lxcCloudInitResult returns an error if the result.json of cloud-init
+// contains any errors.
+func lxcCloudInitResult(output string) error {
+	var result struct {
+		V1 struct {
+			Errors []string `json:"errors"`
+		} `json:"v1"`
+	}
+
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return fmt.Errorf("Unable to parse %s: %s", lxcCloudInitResultFile, err)
+	}
+
+	if len(result.V1.Errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(result.V1.Errors, "; "))
+	}
+
+	return nil
+}
+
+// lxcValidateCloudInit rejects waiting for cloud-init in a container that
+// is never started.
+func lxcValidateCloudInit(d *schema.ResourceDiff, meta interface{}) error {
+	if d.Get("wait_for_cloud_init").(bool) && d.Get("state").(string) == "stopped" && len(d.Get("exec").([]interface{})) == 0 {
+		return fmt.Errorf("wait_for_cloud_init requires the container to be started, either by a state other than stopped or by exec")
+	}
+
+	return nil
+}
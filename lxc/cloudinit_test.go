@@ -0,0 +1,84 @@
+package lxc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLXCWriteCloudInitSeed(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "terraform-provider-lxc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	idmap := lxcIDMap{
+		lxcIDMapEntry{"u", 0, os.Getuid(), 1},
+		lxcIDMapEntry{"g", 0, os.Getgid(), 1},
+	}
+
+	// /var/lib/cloud is an absolute link, which has to be resolved inside
+	// the rootfs and not on the host.
+	if err := os.MkdirAll(filepath.Join(rootfs, "var", "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(rootfs, "srv", "cloud"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/srv/cloud", filepath.Join(rootfs, "var", "lib", "cloud")); err != nil {
+		t.Fatal(err)
+	}
+
+	seed := map[string]string{
+		"user-data":      "#cloud-config\n",
+		"meta-data":      "instance-id: test\n",
+		"network-config": "",
+	}
+
+	if err := lxcWriteCloudInitSeed(rootfs, idmap, seed); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	dir := filepath.Join(rootfs, "srv", "cloud", "seed", "nocloud-net")
+	for name, expected := range seed {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if expected == "" {
+			if !os.IsNotExist(err) {
+				t.Fatalf("Expected %s not to be written", name)
+			}
+			continue
+		}
+
+		if err != nil || string(content) != expected {
+			t.Fatalf("Expected %s to be %q, got %q (%v)", name, expected, content, err)
+		}
+	}
+
+	// a link that climbs above the rootfs is refused
+	if err := os.Remove(filepath.Join(rootfs, "var", "lib", "cloud")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../../../../tmp", filepath.Join(rootfs, "var", "lib", "cloud")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lxcWriteCloudInitSeed(rootfs, idmap, seed); err == nil {
+		t.Fatalf("Expected an error writing through a link that leaves the rootfs")
+	}
+}
+
+func TestLXCCloudInitResult(t *testing.T) {
+	if err := lxcCloudInitResult(`{"v1": {"datasource": "DataSourceNoCloud", "errors": []}}`); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if err := lxcCloudInitResult(`{"v1": {"datasource": null, "errors": ["no datasource found"]}}`); err == nil {
+		t.Fatalf("Expected an error for a failed cloud-init run")
+	}
+
+	if err := lxcCloudInitResult("cat: result.json: No such file or directory"); err == nil {
+		t.Fatalf("Expected an error for an invalid result")
+	}
+}
@@ -3,4 +3,8 @@ package lxc
 type Config struct {
 	LXCPath    string
 	LXCLogPath string
+
+	// LegacyConfig is set when liblxc only understands the config
+	// keys used before LXC 2.1.
+	LegacyConfig bool
 }
@@ -0,0 +1,100 @@
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/google/shlex"
+	"github.com/hashicorp/terraform/helper/schema"
+	"gopkg.in/lxc/go-lxc.v2"
+)
+
+// lxcExecCommands runs the commands of the exec attribute in the container
+// in order. It stops at the first command that cannot be parsed or that
+// exits with a non-zero status, and returns the output of every command
+// that was run.
+func lxcExecCommands(c *lxc.Container, d *schema.ResourceData) ([]string, error) {
+	options := lxc.DefaultAttachOptions
+	if v, ok := d.GetOk("exec_cwd"); ok {
+		options.Cwd = v.(string)
+	}
+	if v, ok := d.GetOk("exec_uid"); ok {
+		options.UID = v.(int)
+	}
+	if v, ok := d.GetOk("exec_gid"); ok {
+		options.GID = v.(int)
+	}
+	for k, v := range d.Get("exec_env").(map[string]interface{}) {
+		options.Env = append(options.Env, fmt.Sprintf("%s=%s", k, v.(string)))
+	}
+
+	var outputs []string
+	for _, command := range d.Get("exec").([]interface{}) {
+		args, err := shlex.Split(command.(string))
+		if err != nil {
+			return outputs, fmt.Errorf("Error parsing arguments for command %s: %s", command.(string), err)
+		}
+
+		log.Printf("[INFO] Running command in container %s : %s\n", c.Name(), command.(string))
+		output, status, err := lxcRunCommand(c, args, options)
+		outputs = append(outputs, output)
+		log.Printf("[DEBUG] Output of command %s:\n%s", command.(string), output)
+		if err != nil {
+			return outputs, fmt.Errorf("Error running command %s: %s", command.(string), err)
+		}
+
+		if err := lxcCommandError(command.(string), status, output); err != nil {
+			return outputs, err
+		}
+	}
+
+	return outputs, nil
+}
+
+// lxcRunCommand runs a command in the container and returns its combined
+// stdout and stderr along with its wait status.
+func lxcRunCommand(c *lxc.Container, args []string, options lxc.AttachOptions) (string, syscall.WaitStatus, error) {
+	f, err := ioutil.TempFile("", "terraform-provider-lxc")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	options.StdoutFd = f.Fd()
+	options.StderrFd = f.Fd()
+
+	// the status is the raw status returned by waitpid
+	status, err := c.RunCommandStatus(args, options)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if status < 0 {
+		return "", 0, fmt.Errorf("Unable to attach to container %s", c.Name())
+	}
+
+	output, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return "", 0, err
+	}
+
+	return string(output), syscall.WaitStatus(status), nil
+}
+
+// lxcCommandError returns an error if a command did not exit with a zero
+// status.
+func lxcCommandError(command string, status syscall.WaitStatus, output string) error {
+	switch {
+	case status.Signaled():
+		return fmt.Errorf("Command %s was killed by signal %s: %s", command, status.Signal(), strings.TrimSpace(output))
+	case status.ExitStatus() != 0:
+		return fmt.Errorf("Command %s exited with status %d: %s", command, status.ExitStatus(), strings.TrimSpace(output))
+	}
+
+	return nil
+}
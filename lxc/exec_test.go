@@ -0,0 +1,24 @@
+package lxc
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestLXCCommandError(t *testing.T) {
+	if err := lxcCommandError("true", syscall.WaitStatus(0), ""); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// waitpid reports exit 1 as 256
+	err := lxcCommandError("false", syscall.WaitStatus(256), "failed\n")
+	if err == nil || err.Error() != "Command false exited with status 1: failed" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = lxcCommandError("sleep 60", syscall.WaitStatus(syscall.SIGKILL), "")
+	if err == nil || !strings.Contains(err.Error(), "killed by signal killed") {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"gopkg.in/lxc/go-lxc.v2"
+)
+
+// lxcFileSchema returns the schema of file blocks. Files are only written
+// when the container is created, so changing them later does nothing and
+// does not replace the container.
+func lxcFileSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"path": &schema.Schema{
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: lxcValidateFilePath,
+				},
+				"content": &schema.Schema{
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"source": &schema.Schema{
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"mode": &schema.Schema{
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      "0644",
+					ValidateFunc: lxcValidateFileMode,
+				},
+				"owner": &schema.Schema{
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      "0:0",
+					ValidateFunc: lxcValidateFileOwner,
+				},
+			},
+		},
+	}
+}
+
+// lxcWriteFiles writes the file blocks into the rootfs of a stopped
+// container. Owners are shifted according to the container's id map so
+// that files in unprivileged containers are owned by the right user.
+func lxcWriteFiles(c *lxc.Container, d *schema.ResourceData) error {
+	files := d.Get("file").([]interface{})
+	if len(files) == 0 {
+		return nil
+	}
+
+	rootfs, err := lxcRootfsPath(c)
+	if err != nil {
+		return err
+	}
+
+	idmap, err := lxcParseIDMap(c)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		file := f.(map[string]interface{})
+		content := file["content"].(string)
+		source := file["source"].(string)
+
+		if (content == "") == (source == "") {
+			return fmt.Errorf("Exactly one of content or source must be set for file %s", file["path"].(string))
+		}
+
+		if source != "" {
+			b, err := ioutil.ReadFile(source)
+			if err != nil {
+				return fmt.Errorf("Error reading source of file %s: %s", file["path"].(string), err)
+			}
+			content = string(b)
+		}
+
+		mode, _ := strconv.ParseUint(file["mode"].(string), 8, 32)
+		uid, gid, _ := lxcParseFileOwner(file["owner"].(string))
+
+		log.Printf("[INFO] Writing file %s in container %s", file["path"].(string), c.Name())
+		if err := lxcWriteFile(rootfs, idmap, file["path"].(string), content, os.FileMode(mode), uid, gid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lxcWriteFile writes a single file into a rootfs. The uid and gid are
+// the owner inside the container.
+func lxcWriteFile(rootfs string, idmap lxcIDMap, path, content string, mode os.FileMode, uid, gid int) error {
+	rootUID, rootGID, err := idmap.shift(0, 0)
+	if err != nil {
+		return err
+	}
+
+	uid, gid, err = idmap.shift(uid, gid)
+	if err != nil {
+		return err
+	}
+
+	path = filepath.Clean("/" + path)
+	if path == "/" {
+		return fmt.Errorf("Invalid file path %s", path)
+	}
+
+	dir, err := lxcSecureJoin(rootfs, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	if err := lxcMkdirAll(filepath.Clean(rootfs), dir, rootUID, rootGID); err != nil {
+		return err
+	}
+
+	// a symlink in place of the file is replaced rather than followed
+	path = filepath.Join(dir, filepath.Base(path))
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|syscall.O_NOFOLLOW, mode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(content); err != nil {
+		return err
+	}
+
+	// OpenFile does not change the mode of an existing file and is
+	// subject to the umask.
+	if err := f.Chmod(mode); err != nil {
+		return err
+	}
+
+	if err := f.Chown(uid, gid); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// lxcSecureJoin joins a path to a rootfs and resolves every symlink in it
+// the way the container would see it, so that the result is always inside
+// the rootfs. Absolute link targets are resolved relative to the rootfs.
+// Links that climb above the rootfs are refused. Missing components are
+// kept as they are.
+func lxcSecureJoin(rootfs, path string) (string, error) {
+	components := strings.Split(filepath.Clean("/"+path), "/")
+	resolved := ""
+	links := 0
+
+	for len(components) > 0 {
+		component := components[0]
+		components = components[1:]
+
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			if resolved == "" {
+				return "", fmt.Errorf("Path %s leads outside of the rootfs %s", path, rootfs)
+			}
+			resolved = filepath.Dir(resolved)
+			if resolved == "." {
+				resolved = ""
+			}
+			continue
+		}
+
+		next := filepath.Join(resolved, component)
+		fi, err := os.Lstat(filepath.Join(rootfs, next))
+		if os.IsNotExist(err) {
+			resolved = next
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if fi.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		links++
+		if links > 255 {
+			return "", fmt.Errorf("Too many symlinks in path %s", path)
+		}
+
+		target, err := os.Readlink(filepath.Join(rootfs, next))
+		if err != nil {
+			return "", err
+		}
+
+		if filepath.IsAbs(target) {
+			resolved = ""
+		}
+		components = append(strings.Split(target, "/"), components...)
+	}
+
+	return filepath.Join(rootfs, resolved), nil
+}
+
+// lxcMkdirAll creates a directory inside a rootfs and any missing parents,
+// owned by the given host uid and gid. The path must not contain symlinks,
+// as returned by lxcSecureJoin.
+func lxcMkdirAll(rootfs, path string, uid, gid int) error {
+	if path == rootfs {
+		return nil
+	}
+
+	if fi, err := os.Lstat(path); err == nil {
+		if !fi.IsDir() {
+			return fmt.Errorf("%s is not a directory", path)
+		}
+		return nil
+	}
+
+	if err := lxcMkdirAll(rootfs, filepath.Dir(path), uid, gid); err != nil {
+		return err
+	}
+
+	if err := os.Mkdir(path, 0755); err != nil {
+		return err
+	}
+
+	return os.Lchown(path, uid, gid)
+}
+
+// lxcRootfsPath returns the host path of the container's rootfs. Only
+// backends that keep the rootfs as a directory on the host are supported.
+func lxcRootfsPath(c *lxc.Container) (string, error) {
+	var rootfs string
+	for _, key := range []string{"lxc.rootfs.path", "lxc.rootfs"} {
+		if v := c.ConfigItem(key); len(v) > 0 && v[0] != "" {
+			rootfs = v[0]
+			break
+		}
+	}
+
+	if strings.HasPrefix(rootfs, "/") {
+		return rootfs, nil
+	}
+
+	parts := strings.Split(rootfs, ":")
+	switch parts[0] {
+	case "dir", "btrfs":
+		return strings.Join(parts[1:], ":"), nil
+	case "overlay", "overlayfs", "aufs":
+		// changes go to the upper directory
+		return parts[len(parts)-1], nil
+	}
+
+	// zfs datasets are mounted on the default rootfs directory
+	if lxcReadBackend(c) == "zfs" {
+		return filepath.Join(c.ConfigPath(), c.Name(), "rootfs"), nil
+	}
+
+	return "", fmt.Errorf("Unable to write files into the rootfs %s of container %s", rootfs, c.Name())
+}
+
+type lxcIDMapEntry struct {
+	kind      string
+	container int
+	host      int
+	size      int
+}
+
+type lxcIDMap []lxcIDMapEntry
+
+// lxcParseIDMap reads the id map of the container. The map is empty for
+// privileged containers.
+func lxcParseIDMap(c *lxc.Container) (lxcIDMap, error) {
+	var idmap lxcIDMap
+	for _, key := range []string{"lxc.idmap", "lxc.id_map"} {
+		for _, v := range c.ConfigItem(key) {
+			fields := strings.Fields(v)
+			if len(fields) != 4 {
+				continue
+			}
+
+			var ids [3]int
+			for i := range ids {
+				id, err := strconv.Atoi(fields[i+1])
+				if err != nil {
+					return nil, fmt.Errorf("Invalid id map %s: %s", v, err)
+				}
+				ids[i] = id
+			}
+
+			idmap = append(idmap, lxcIDMapEntry{fields[0], ids[0], ids[1], ids[2]})
+		}
+
+		if len(idmap) > 0 {
+			break
+		}
+	}
+
+	return idmap, nil
+}
+
+// shift maps a uid and gid inside the container to the host.
+func (m lxcIDMap) shift(uid, gid int) (int, int, error) {
+	if len(m) == 0 {
+		return uid, gid, nil
+	}
+
+	hostUID, hostGID := -1, -1
+	for _, e := range m {
+		if e.kind == "u" && uid >= e.container && uid < e.container+e.size {
+			hostUID = e.host + uid - e.container
+		}
+		if e.kind == "g" && gid >= e.container && gid < e.container+e.size {
+			hostGID = e.host + gid - e.container
+		}
+	}
+
+	if hostUID == -1 || hostGID == -1 {
+		return 0, 0, fmt.Errorf("Owner %d:%d is not mapped in the container", uid, gid)
+	}
+
+	return hostUID, hostGID, nil
+}
+
+func lxcParseFileOwner(owner string) (int, int, error) {
+	parts := strings.Split(owner, ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("Invalid owner %s", owner)
+	}
+
+	uid, err := strconv.Atoi(parts[0])
+	if err != nil || uid < 0 {
+		return 0, 0, fmt.Errorf("Invalid owner %s", owner)
+	}
+
+	gid, err := strconv.Atoi(parts[1])
+	if err != nil || gid < 0 {
+		return 0, 0, fmt.Errorf("Invalid owner %s", owner)
+	}
+
+	return uid, gid, nil
+}
+
+func lxcValidateFileMode(v interface{}, k string) (ws []string, errors []error) {
+	if _, err := strconv.ParseUint(v.(string), 8, 32); err != nil {
+		errors = append(errors, fmt.Errorf("%s must be an octal file mode such as 0644", k))
+	}
+
+	return
+}
+
+func lxcValidateFileOwner(v interface{}, k string) (ws []string, errors []error) {
+	if _, _, err := lxcParseFileOwner(v.(string)); err != nil {
+		errors = append(errors, fmt.Errorf("%s must be a numeric uid:gid pair such as 0:0", k))
+	}
+
+	return
+}
+
+func lxcValidateFilePath(v interface{}, k string) (ws []string, errors []error) {
+	if !filepath.IsAbs(v.(string)) || filepath.Clean(v.(string)) == "/" {
+		errors = append(errors, fmt.Errorf("%s must be an absolute path of a file inside the container", k))
+	}
+
+	return
+}
@@ -0,0 +1,123 @@
+package lxc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLXCIDMapShift(t *testing.T) {
+	idmap := lxcIDMap{
+		lxcIDMapEntry{"u", 0, 100000, 65536},
+		lxcIDMapEntry{"g", 0, 200000, 65536},
+	}
+
+	uid, gid, err := idmap.shift(1000, 33)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if uid != 101000 || gid != 200033 {
+		t.Fatalf("Expected 101000:200033, got %d:%d", uid, gid)
+	}
+
+	if _, _, err := idmap.shift(70000, 0); err == nil {
+		t.Fatalf("Expected an error for an unmapped uid")
+	}
+
+	uid, gid, err = lxcIDMap{}.shift(1000, 33)
+	if err != nil || uid != 1000 || gid != 33 {
+		t.Fatalf("Expected 1000:33 without an id map, got %d:%d (%v)", uid, gid, err)
+	}
+}
+
+func TestLXCParseFileOwner(t *testing.T) {
+	uid, gid, err := lxcParseFileOwner("1000:33")
+	if err != nil || uid != 1000 || gid != 33 {
+		t.Fatalf("Expected 1000:33, got %d:%d (%v)", uid, gid, err)
+	}
+
+	for _, owner := range []string{"", "root:root", "1000", "-1:0", "0:0:0"} {
+		if _, _, err := lxcParseFileOwner(owner); err == nil {
+			t.Fatalf("Expected an error parsing %s", owner)
+		}
+	}
+}
+
+func TestLXCWriteFile(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "terraform-provider-lxc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	// map root of the container to the current user, so that the test
+	// does not have to run as root.
+	idmap := lxcIDMap{
+		lxcIDMapEntry{"u", 0, os.Getuid(), 1},
+		lxcIDMapEntry{"g", 0, os.Getgid(), 1},
+	}
+
+	for _, dir := range []string{"run", "var", "etc"} {
+		if err := os.Mkdir(filepath.Join(rootfs, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// absolute links are resolved inside the rootfs
+	if err := os.Symlink("/run", filepath.Join(rootfs, "var", "run")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lxcWriteFile(rootfs, idmap, "/var/run/test/file", "test\n", 0600, 0, 0); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(rootfs, "run", "test", "file"))
+	if err != nil || string(content) != "test\n" {
+		t.Fatalf("Expected the file inside the rootfs, got %q (%v)", content, err)
+	}
+
+	fi, err := os.Stat(filepath.Join(rootfs, "run", "test", "file"))
+	if err != nil || fi.Mode().Perm() != 0600 {
+		t.Fatalf("Expected mode 0600, got %v (%v)", fi, err)
+	}
+
+	// a symlink in place of the file is replaced
+	outside := filepath.Join(rootfs, "outside")
+	if err := os.Symlink(outside, filepath.Join(rootfs, "etc", "hostname")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lxcWriteFile(rootfs, idmap, "/etc/hostname", "test\n", 0644, 0, 0); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if _, err := os.Lstat(outside); !os.IsNotExist(err) {
+		t.Fatalf("Expected the symlink not to be followed")
+	}
+
+	// links that climb above the rootfs are refused
+	if err := os.Symlink("../../../../..", filepath.Join(rootfs, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lxcWriteFile(rootfs, idmap, "/escape/tmp/file", "test\n", 0644, 0, 0); err == nil {
+		t.Fatalf("Expected an error writing through a link that leaves the rootfs")
+	}
+}
+
+func TestLXCValidateFilePath(t *testing.T) {
+	for _, path := range []string{"/etc/hostname", "/root/.ssh/authorized_keys"} {
+		if _, errs := lxcValidateFilePath(path, "path"); len(errs) > 0 {
+			t.Fatalf("Unexpected errors for %s: %v", path, errs)
+		}
+	}
+
+	for _, path := range []string{"", "/", "etc/hostname"} {
+		if _, errs := lxcValidateFilePath(path, "path"); len(errs) == 0 {
+			t.Fatalf("Expected an error for %q", path)
+		}
+	}
+}
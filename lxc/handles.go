@@ -0,0 +1,98 @@
+package lxc
+
+import (
+	"log"
+	"time"
+
+	"gopkg.in/lxc/go-lxc.v2"
+)
+
+// lxcHandles opens the container handles of a single operation. A
+// container is opened once and its handle is shared by every call that
+// asks for it. All handles are released together when the operation is
+// done.
+type lxcHandles struct {
+	lxcpath    string
+	containers map[string]*lxc.Container
+}
+
+func newLXCHandles(config *Config) *lxcHandles {
+	return &lxcHandles{
+		lxcpath:    config.LXCPath,
+		containers: make(map[string]*lxc.Container),
+	}
+}
+
+// get returns the handle of the named container. The container does not
+// have to exist yet.
+func (h *lxcHandles) get(name string) (*lxc.Container, error) {
+	if c, ok := h.containers[name]; ok {
+		return c, nil
+	}
+
+	c, err := lxc.NewContainer(name, h.lxcpath)
+	if err != nil {
+		return nil, err
+	}
+
+	h.containers[name] = c
+	return c, nil
+}
+
+// forget releases the handle of the named container, for example after
+// the container was renamed. The next get opens a new handle.
+func (h *lxcHandles) forget(name string) {
+	if c, ok := h.containers[name]; ok {
+		lxcRelease(c)
+		delete(h.containers, name)
+	}
+}
+
+// release releases all handles.
+func (h *lxcHandles) release() {
+	for name, c := range h.containers {
+		lxcRelease(c)
+		delete(h.containers, name)
+	}
+}
+
+// lxcReloadConfig makes a handle read the container's config file again,
+// so that it sees changes that were written since it was opened.
+func lxcReloadConfig(c *lxc.Container) error {
+	// liblxc adds the contents of a loaded file to the config of the
+	// handle, so the old config is cleared first.
+	c.ClearConfig()
+	return c.LoadConfigFile(c.ConfigFileName())
+}
+
+// lxcRunWithTimeout runs a blocking liblxc call for the named container
+// and stops waiting for it when it takes longer than the timeout. It
+// reports whether the call finished in time.
+func lxcRunWithTimeout(name, lxcpath string, timeout time.Duration, call func(c *lxc.Container) error) (bool, error) {
+	// liblxc can not cancel the call, so it is left running in the
+	// background when the timeout expires. It uses a handle of its own,
+	// since the handle is locked until the call is done.
+	c, err := lxc.NewContainer(name, lxcpath)
+	if err != nil {
+		return false, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer lxcRelease(c)
+		done <- call(c)
+	}()
+
+	select {
+	case err := <-done:
+		return true, err
+	case <-time.After(timeout):
+		return false, nil
+	}
+}
+
+func lxcRelease(c *lxc.Container) {
+	if err := c.Release(); err != nil {
+		log.Printf("[WARN] Unable to release container %s: %s", c.Name(), err)
+	}
+}
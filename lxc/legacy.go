@@ -0,0 +1,116 @@
+package lxc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lxcLegacyKeys maps config keys that were renamed in LXC 2.1 to their
+// new names. The old names were removed in LXC 3.0.
+var lxcLegacyKeys = map[string]string{
+	"lxc.aa_allow_incomplete": "lxc.apparmor.allow_incomplete",
+	"lxc.aa_profile":          "lxc.apparmor.profile",
+	"lxc.console":             "lxc.console.path",
+	"lxc.devttydir":           "lxc.tty.dir",
+	"lxc.haltsignal":          "lxc.signal.halt",
+	"lxc.id_map":              "lxc.idmap",
+	"lxc.init_cmd":            "lxc.init.cmd",
+	"lxc.init_gid":            "lxc.init.gid",
+	"lxc.init_uid":            "lxc.init.uid",
+	"lxc.logfile":             "lxc.log.file",
+	"lxc.loglevel":            "lxc.log.level",
+	"lxc.mount":               "lxc.mount.fstab",
+	"lxc.pts":                 "lxc.pty.max",
+	"lxc.rebootsignal":        "lxc.signal.reboot",
+	"lxc.rootfs":              "lxc.rootfs.path",
+	"lxc.se_context":          "lxc.selinux.context",
+	"lxc.seccomp":             "lxc.seccomp.profile",
+	"lxc.stopsignal":          "lxc.signal.stop",
+	"lxc.syslog":              "lxc.log.syslog",
+	"lxc.tty":                 "lxc.tty.max",
+	"lxc.utsname":             "lxc.uts.name",
+}
+
+// lxcLegacyConfig reports whether a liblxc version only understands the
+// config keys used before LXC 2.1, such as lxc.network.*. Versions that
+// can not be parsed are treated as legacy.
+func lxcLegacyConfig(version string) bool {
+	var major, minor int
+	if _, err := fmt.Sscanf(version, "%d.%d", &major, &minor); err != nil {
+		return true
+	}
+
+	return major < 2 || (major == 2 && minor < 1)
+}
+
+// lxcTranslateKey translates a legacy config key into its current name.
+// Unindexed lxc.network.* keys apply to the interface with the given
+// index, just like they applied to the last defined interface before.
+func lxcTranslateKey(key string, nicIndex int) string {
+	if strings.HasPrefix(key, "lxc.network.") {
+		rest := strings.TrimPrefix(key, "lxc.network.")
+		parts := strings.SplitN(rest, ".", 2)
+		if index, err := strconv.Atoi(parts[0]); err == nil && len(parts) == 2 {
+			return lxcNetworkKey(false, index, lxcTranslateNetworkKey(parts[1]))
+		}
+
+		if nicIndex < 0 {
+			nicIndex = 0
+		}
+
+		return lxcNetworkKey(false, nicIndex, lxcTranslateNetworkKey(rest))
+	}
+
+	if strings.HasPrefix(key, "lxc.limit.") {
+		return "lxc.prlimit." + strings.TrimPrefix(key, "lxc.limit.")
+	}
+
+	if newKey, ok := lxcLegacyKeys[key]; ok {
+		return newKey
+	}
+
+	return key
+}
+
+// lxcTranslateNetworkKey translates a legacy key of a network interface,
+// without the lxc.network prefix, into its current name.
+func lxcTranslateNetworkKey(key string) string {
+	switch key {
+	case "ipv4":
+		return "ipv4.address"
+	case "ipv6":
+		return "ipv6.address"
+	}
+
+	return key
+}
+
+// lxcNetworkKey returns the indexed config key of a network interface.
+func lxcNetworkKey(legacy bool, index int, key string) string {
+	if legacy {
+		return fmt.Sprintf("lxc.network.%d.%s", index, key)
+	}
+
+	return fmt.Sprintf("lxc.net.%d.%s", index, key)
+}
+
+// lxcParseNetworkKey splits an indexed lxc.net.* key into the index of the
+// interface and the key of the interface.
+func lxcParseNetworkKey(key string) (int, string, bool) {
+	if !strings.HasPrefix(key, "lxc.net.") {
+		return 0, "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(key, "lxc.net."), ".", 2)
+	if len(parts) != 2 {
+		return 0, "", false
+	}
+
+	index, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", false
+	}
+
+	return index, parts[1], true
+}
@@ -0,0 +1,54 @@
+package lxc
+
+import (
+	"testing"
+)
+
+func TestLXCLegacyConfig(t *testing.T) {
+	versions := map[string]bool{
+		"1.1.5":  true,
+		"2.0.8":  true,
+		"2.1.1":  false,
+		"3.0.3":  false,
+		"4.0.12": false,
+		"devel":  true,
+	}
+
+	for version, expected := range versions {
+		if actual := lxcLegacyConfig(version); actual != expected {
+			t.Fatalf("Expected legacy config for %s to be %t", version, expected)
+		}
+	}
+}
+
+func TestLXCTranslateKey(t *testing.T) {
+	keys := map[string]string{
+		"lxc.utsname":          "lxc.uts.name",
+		"lxc.limit.nofile":     "lxc.prlimit.nofile",
+		"lxc.network.mtu":      "lxc.net.2.mtu",
+		"lxc.network.ipv4":     "lxc.net.2.ipv4.address",
+		"lxc.network.0.ipv6":   "lxc.net.0.ipv6.address",
+		"lxc.network.1.link":   "lxc.net.1.link",
+		"lxc.start.auto":       "lxc.start.auto",
+		"lxc.cgroup.cpu.share": "lxc.cgroup.cpu.share",
+	}
+
+	for key, expected := range keys {
+		if actual := lxcTranslateKey(key, 2); actual != expected {
+			t.Fatalf("Expected %s to be translated to %s, got %s", key, expected, actual)
+		}
+	}
+}
+
+func TestLXCParseNetworkKey(t *testing.T) {
+	index, key, ok := lxcParseNetworkKey("lxc.net.1.ipv4.address")
+	if !ok || index != 1 || key != "ipv4.address" {
+		t.Fatalf("Unexpected result: %d %s %t", index, key, ok)
+	}
+
+	for _, k := range []string{"lxc.network.1.link", "lxc.net.x.link", "lxc.net.1"} {
+		if _, _, ok := lxcParseNetworkKey(k); ok {
+			t.Fatalf("Expected %s not to be parsed", k)
+		}
+	}
+}
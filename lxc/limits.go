@@ -0,0 +1,267 @@
+package lxc
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/jtopjian/terraform-provider-lxc/lxcconfig"
+)
+
+func lxcLimitsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"memory": &schema.Schema{
+					Type:         schema.TypeString,
+					Optional:     true,
+					ValidateFunc: lxcValidateSize,
+				},
+				"memory_swap": &schema.Schema{
+					Type:         schema.TypeString,
+					Optional:     true,
+					ValidateFunc: lxcValidateSize,
+				},
+				"cpu_shares": &schema.Schema{
+					Type:         schema.TypeInt,
+					Optional:     true,
+					ValidateFunc: lxcValidateIntBetween(2, 262144),
+				},
+				"cpuset": &schema.Schema{
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"pids_max": &schema.Schema{
+					Type:         schema.TypeInt,
+					Optional:     true,
+					ValidateFunc: lxcValidateIntBetween(0, math.MaxInt32),
+				},
+				"blkio_weight": &schema.Schema{
+					Type:         schema.TypeInt,
+					Optional:     true,
+					ValidateFunc: lxcValidateIntBetween(10, 1000),
+				},
+			},
+		},
+	}
+}
+
+// lxcCgroupV2 reports whether the host uses the unified cgroup hierarchy.
+func lxcCgroupV2() bool {
+	_, err := os.Stat("/sys/fs/cgroup/cgroup.controllers")
+	return err == nil
+}
+
+// lxcLimitsOptions translates a limits block into the cgroup config keys
+// understood by the host.
+func lxcLimitsOptions(l []interface{}) (map[string]string, error) {
+	options := make(map[string]string)
+	if len(l) == 0 || l[0] == nil {
+		return options, nil
+	}
+
+	limits := l[0].(map[string]interface{})
+	memory := limits["memory"].(string)
+	memorySwap := limits["memory_swap"].(string)
+	cpuShares := limits["cpu_shares"].(int)
+	cpuset := limits["cpuset"].(string)
+	pidsMax := limits["pids_max"].(int)
+	blkioWeight := limits["blkio_weight"].(int)
+
+	if memorySwap != "" && memory == "" {
+		return nil, fmt.Errorf("memory_swap can only be set together with memory")
+	}
+
+	if !lxcCgroupV2() {
+		if memory != "" {
+			options["lxc.cgroup.memory.limit_in_bytes"] = memory
+		}
+		if memorySwap != "" {
+			options["lxc.cgroup.memory.memsw.limit_in_bytes"] = memorySwap
+		}
+		if cpuShares != 0 {
+			options["lxc.cgroup.cpu.shares"] = strconv.Itoa(cpuShares)
+		}
+		if cpuset != "" {
+			options["lxc.cgroup.cpuset.cpus"] = cpuset
+		}
+		if pidsMax != 0 {
+			options["lxc.cgroup.pids.max"] = strconv.Itoa(pidsMax)
+		}
+		if blkioWeight != 0 {
+			options["lxc.cgroup.blkio.weight"] = strconv.Itoa(blkioWeight)
+		}
+
+		return options, nil
+	}
+
+	if memory != "" {
+		options["lxc.cgroup2.memory.max"] = memory
+	}
+	// memory_swap follows the cgroup v1 semantics of memory plus swap,
+	// while memory.swap.max only limits the swap usage.
+	if memorySwap != "" {
+		m, err := lxcParseSize(memory)
+		if err != nil {
+			return nil, err
+		}
+		s, err := lxcParseSize(memorySwap)
+		if err != nil {
+			return nil, err
+		}
+		if s < m {
+			return nil, fmt.Errorf("memory_swap must not be smaller than memory")
+		}
+		options["lxc.cgroup2.memory.swap.max"] = strconv.FormatInt(s-m, 10)
+	}
+	// the conversions of shares and weights are the same ones used by
+	// other container runtimes.
+	if cpuShares != 0 {
+		weight := 1 + ((cpuShares-2)*9999)/262142
+		options["lxc.cgroup2.cpu.weight"] = strconv.Itoa(weight)
+	}
+	if cpuset != "" {
+		options["lxc.cgroup2.cpuset.cpus"] = cpuset
+	}
+	if pidsMax != 0 {
+		options["lxc.cgroup2.pids.max"] = strconv.Itoa(pidsMax)
+	}
+	if blkioWeight != 0 {
+		weight := 1 + ((blkioWeight-10)*9999)/990
+		options["lxc.cgroup2.io.weight"] = strconv.Itoa(weight)
+	}
+
+	return options, nil
+}
+
+// lxcReadLimits turns the limits entries of a config file back into a
+// limits block. The cgroup v2 weights can not be converted back exactly,
+// so the current block is kept as long as it still results in the same
+// entries.
+func lxcReadLimits(f *lxcconfig.File, current []interface{}) ([]interface{}, error) {
+	entries := make(map[string]string)
+	for _, entry := range f.Entries() {
+		entries[entry.Key] = entry.Value
+	}
+
+	if len(entries) == 0 {
+		return []interface{}{}, nil
+	}
+
+	if options, err := lxcLimitsOptions(current); err == nil && reflect.DeepEqual(options, entries) {
+		return current, nil
+	}
+
+	limits := map[string]interface{}{
+		"memory":       "",
+		"memory_swap":  "",
+		"cpu_shares":   0,
+		"cpuset":       "",
+		"pids_max":     0,
+		"blkio_weight": 0,
+	}
+
+	atoi := func(k string) (int, error) {
+		v, err := strconv.Atoi(entries[k])
+		if err != nil {
+			return 0, fmt.Errorf("Invalid value for %s: %s", k, entries[k])
+		}
+		return v, nil
+	}
+
+	for k, v := range entries {
+		var err error
+		switch k {
+		case "lxc.cgroup.memory.limit_in_bytes", "lxc.cgroup2.memory.max":
+			limits["memory"] = v
+		case "lxc.cgroup.memory.memsw.limit_in_bytes":
+			limits["memory_swap"] = v
+		case "lxc.cgroup2.memory.swap.max":
+			// memory.swap.max only holds the swap part of memory_swap
+			var m, s int64
+			if m, err = lxcParseSize(entries["lxc.cgroup2.memory.max"]); err != nil {
+				return nil, err
+			}
+			if s, err = lxcParseSize(v); err != nil {
+				return nil, err
+			}
+			limits["memory_swap"] = strconv.FormatInt(m+s, 10)
+		case "lxc.cgroup.cpu.shares":
+			limits["cpu_shares"], err = atoi(k)
+		case "lxc.cgroup2.cpu.weight":
+			var weight int
+			weight, err = atoi(k)
+			limits["cpu_shares"] = 2 + ((weight-1)*262142)/9999
+		case "lxc.cgroup.cpuset.cpus", "lxc.cgroup2.cpuset.cpus":
+			limits["cpuset"] = v
+		case "lxc.cgroup.pids.max", "lxc.cgroup2.pids.max":
+			limits["pids_max"], err = atoi(k)
+		case "lxc.cgroup.blkio.weight":
+			limits["blkio_weight"], err = atoi(k)
+		case "lxc.cgroup2.io.weight":
+			var weight int
+			weight, err = atoi(k)
+			limits["blkio_weight"] = 10 + ((weight-1)*990)/9999
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return []interface{}{limits}, nil
+}
+
+// lxcParseSize parses a size such as 512M into bytes.
+func lxcParseSize(s string) (int64, error) {
+	size := strings.TrimSpace(s)
+	multiplier := int64(1)
+
+	if len(size) > 0 {
+		switch strings.ToUpper(size[len(size)-1:]) {
+		case "K":
+			multiplier = 1 << 10
+		case "M":
+			multiplier = 1 << 20
+		case "G":
+			multiplier = 1 << 30
+		case "T":
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			size = size[:len(size)-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid size %s", s)
+	}
+
+	return n * multiplier, nil
+}
+
+func lxcValidateSize(v interface{}, k string) (ws []string, errors []error) {
+	if _, err := lxcParseSize(v.(string)); err != nil {
+		errors = append(errors, fmt.Errorf("%s must be a number of bytes with an optional K, M, G or T suffix", k))
+	}
+
+	return
+}
+
+func lxcValidateIntBetween(min, max int) schema.SchemaValidateFunc {
+	return func(v interface{}, k string) (ws []string, errors []error) {
+		if i := v.(int); i < min || i > max {
+			errors = append(errors, fmt.Errorf("%s must be between %d and %d", k, min, max))
+		}
+
+		return
+	}
+}
@@ -0,0 +1,131 @@
+package lxc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/jtopjian/terraform-provider-lxc/lxcconfig"
+)
+
+func TestLXCParseSize(t *testing.T) {
+	sizes := map[string]int64{
+		"0":    0,
+		"1024": 1024,
+		"512K": 512 * 1024,
+		"512M": 512 * 1024 * 1024,
+		"2g":   2 * 1024 * 1024 * 1024,
+		" 1T ": 1024 * 1024 * 1024 * 1024,
+	}
+
+	for s, expected := range sizes {
+		actual, err := lxcParseSize(s)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s: %s", s, err)
+		}
+
+		if actual != expected {
+			t.Fatalf("Expected %s to be %d bytes, got %d", s, expected, actual)
+		}
+	}
+
+	for _, s := range []string{"", "M", "-1", "1.5G", "12X"} {
+		if _, err := lxcParseSize(s); err == nil {
+			t.Fatalf("Expected an error parsing %s", s)
+		}
+	}
+}
+
+func TestLXCReadLimits(t *testing.T) {
+	f, err := lxcconfig.Parse(strings.NewReader(`lxc.cgroup.memory.limit_in_bytes = 512M
+lxc.cgroup.cpu.shares = 512
+lxc.cgroup.blkio.weight = 100
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{
+		map[string]interface{}{
+			"memory":       "512M",
+			"memory_swap":  "",
+			"cpu_shares":   512,
+			"cpuset":       "",
+			"pids_max":     0,
+			"blkio_weight": 100,
+		},
+	}
+
+	actual, err := lxcReadLimits(f, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error reading limits: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	// the current block is kept if it results in the same entries, even
+	// if the entries can not be converted back exactly.
+	current := []interface{}{
+		map[string]interface{}{
+			"memory":       "1G",
+			"memory_swap":  "2G",
+			"cpu_shares":   1000,
+			"cpuset":       "0-1",
+			"pids_max":     100,
+			"blkio_weight": 500,
+		},
+	}
+
+	options, err := lxcLimitsOptions(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f = lxcconfig.New()
+	for _, k := range lxcSortedKeys(options) {
+		f.Add(k, options[k])
+	}
+
+	actual, err = lxcReadLimits(f, current)
+	if err != nil {
+		t.Fatalf("Unexpected error reading limits: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual, current) {
+		t.Fatalf("Expected %#v, got %#v", current, actual)
+	}
+
+	actual, err = lxcReadLimits(lxcconfig.New(), current)
+	if err != nil || len(actual) != 0 {
+		t.Fatalf("Expected no limits block, got %#v (%v)", actual, err)
+	}
+}
+
+func TestLXCValidateLimits(t *testing.T) {
+	validate := lxcLimitsSchema().Elem.(*schema.Resource).Schema
+
+	invalid := map[string]int{
+		"cpu_shares":   1,
+		"pids_max":     -1,
+		"blkio_weight": 9,
+	}
+	for k, v := range invalid {
+		if _, errs := validate[k].ValidateFunc(v, k); len(errs) == 0 {
+			t.Fatalf("Expected an error for %s = %d", k, v)
+		}
+	}
+
+	valid := map[string]int{
+		"cpu_shares":   2,
+		"pids_max":     0,
+		"blkio_weight": 1000,
+	}
+	for k, v := range valid {
+		if _, errs := validate[k].ValidateFunc(v, k); len(errs) > 0 {
+			t.Fatalf("Unexpected errors for %s = %d: %v", k, v, errs)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package lxc
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func lxcMountSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"source": &schema.Schema{
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"path": &schema.Schema{
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: lxcValidateMountPath,
+				},
+				"type": &schema.Schema{
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "none",
+				},
+				"read_only": &schema.Schema{
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  false,
+				},
+				"create_dir": &schema.Schema{
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  false,
+				},
+				"optional": &schema.Schema{
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  false,
+				},
+			},
+		},
+	}
+}
+
+// lxcMountEntries renders the mount blocks as lxc.mount.entry values. The
+// mount point is relative to the container's rootfs.
+func lxcMountEntries(mounts []interface{}) []string {
+	var entries []string
+	for _, m := range mounts {
+		mount := m.(map[string]interface{})
+		mountType := mount["type"].(string)
+
+		var mountOptions []string
+		if mountType == "none" {
+			mountOptions = append(mountOptions, "bind")
+		}
+		if mount["read_only"].(bool) {
+			mountOptions = append(mountOptions, "ro")
+		} else {
+			mountOptions = append(mountOptions, "rw")
+		}
+		if mount["create_dir"].(bool) {
+			mountOptions = append(mountOptions, "create=dir")
+		}
+		if mount["optional"].(bool) {
+			mountOptions = append(mountOptions, "optional")
+		}
+
+		entries = append(entries, fmt.Sprintf("%s %s %s %s 0 0",
+			lxcEscapeMountField(mount["source"].(string)),
+			lxcEscapeMountField(strings.TrimPrefix(path.Clean(mount["path"].(string)), "/")),
+			mountType,
+			strings.Join(mountOptions, ",")))
+	}
+
+	return entries
+}
+
+// lxcParseMountEntry turns an lxc.mount.entry value back into a mount block.
+func lxcParseMountEntry(entry string) (map[string]interface{}, error) {
+	fields := strings.Fields(entry)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("Invalid mount entry: %s", entry)
+	}
+
+	mount := map[string]interface{}{
+		"source":     lxcUnescapeMountField(fields[0]),
+		"path":       "/" + lxcUnescapeMountField(fields[1]),
+		"type":       fields[2],
+		"read_only":  false,
+		"create_dir": false,
+		"optional":   false,
+	}
+
+	for _, option := range strings.Split(fields[3], ",") {
+		switch option {
+		case "ro":
+			mount["read_only"] = true
+		case "create=dir":
+			mount["create_dir"] = true
+		case "optional":
+			mount["optional"] = true
+		}
+	}
+
+	return mount, nil
+}
+
+// fstab fields are separated by whitespace, so spaces and tabs in paths
+// have to be escaped.
+func lxcEscapeMountField(s string) string {
+	return strings.NewReplacer(" ", "\\040", "\t", "\\011").Replace(s)
+}
+
+func lxcUnescapeMountField(s string) string {
+	return strings.NewReplacer("\\040", " ", "\\011", "\t").Replace(s)
+}
+
+func lxcValidateMountPath(v interface{}, k string) (ws []string, errors []error) {
+	if !path.IsAbs(v.(string)) {
+		errors = append(errors, fmt.Errorf("%s must be an absolute path inside the container", k))
+	}
+
+	return
+}
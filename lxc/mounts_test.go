@@ -0,0 +1,48 @@
+package lxc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLXCMountEntries(t *testing.T) {
+	mounts := []interface{}{
+		map[string]interface{}{
+			"source":     "/srv/my data",
+			"path":       "/mnt/data",
+			"type":       "none",
+			"read_only":  true,
+			"create_dir": true,
+			"optional":   false,
+		},
+		map[string]interface{}{
+			"source":     "tmpfs",
+			"path":       "/tmp",
+			"type":       "tmpfs",
+			"read_only":  false,
+			"create_dir": false,
+			"optional":   true,
+		},
+	}
+
+	expected := []string{
+		"/srv/my\\040data mnt/data none bind,ro,create=dir 0 0",
+		"tmpfs tmp tmpfs rw,optional 0 0",
+	}
+
+	entries := lxcMountEntries(mounts)
+	if !reflect.DeepEqual(entries, expected) {
+		t.Fatalf("Expected %#v, got %#v", expected, entries)
+	}
+
+	for i, entry := range entries {
+		mount, err := lxcParseMountEntry(entry)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s: %s", entry, err)
+		}
+
+		if !reflect.DeepEqual(mount, mounts[i]) {
+			t.Fatalf("Expected %#v, got %#v", mounts[i], mount)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package lxc
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/jtopjian/terraform-provider-lxc/lxcconfig"
+	"gopkg.in/lxc/go-lxc.v2"
+)
+
+func lxcNetworkInterfaceSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"type": &schema.Schema{
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "veth",
+				},
+				"name": &schema.Schema{
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"management": &schema.Schema{
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  false,
+				},
+				"options": &schema.Schema{
+					Type:     schema.TypeMap,
+					Optional: true,
+					Default:  nil,
+				},
+
+				// exported
+				"hwaddr": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"veth_pair": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"ipv4_addresses": &schema.Schema{
+					Type:     schema.TypeList,
+					Computed: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"ipv6_addresses": &schema.Schema{
+					Type:     schema.TypeList,
+					Computed: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+			},
+		},
+	}
+}
+
+// lxcNetworkOffset returns the number of network interfaces defined in the
+// container's own config. The interfaces of the network_interface
+// attribute are added after them.
+func lxcNetworkOffset(c *lxc.Container) (int, error) {
+	config, err := lxcconfig.ParseFile(c.ConfigFileName())
+	if err != nil {
+		return 0, err
+	}
+
+	networks := config.Networks()
+	if len(networks) == 0 {
+		return 0, nil
+	}
+
+	return networks[len(networks)-1].Index + 1, nil
+}
+
+// lxcNetworkInterfaceName returns the name of an interface inside the
+// container. Unnamed interfaces are named by the kernel in order.
+func lxcNetworkInterfaceName(nic map[string]interface{}, index int) string {
+	if name, ok := nic["name"].(string); ok && name != "" {
+		return name
+	}
+
+	return fmt.Sprintf("eth%d", index)
+}
+
+// lxcReadNetworkInterface sets the exported attributes of the interface
+// with the given index.
+func lxcReadNetworkInterface(c *lxc.Container, nic map[string]interface{}, index int, legacy, includeLinkLocal bool) {
+	// the running config holds generated values such as the host side
+	// of a veth pair.
+	configItem := c.ConfigItem
+	if c.Running() {
+		configItem = c.RunningConfigItem
+	}
+
+	nic["hwaddr"] = ""
+	if v := configItem(lxcNetworkKey(legacy, index, "hwaddr")); len(v) > 0 {
+		nic["hwaddr"] = v[0]
+	}
+
+	nic["veth_pair"] = ""
+	if v := configItem(lxcNetworkKey(legacy, index, "veth.pair")); len(v) > 0 {
+		nic["veth_pair"] = v[0]
+	}
+
+	name := lxcNetworkInterfaceName(nic, index)
+	nic["ipv4_addresses"] = []string{}
+	if ipv4s, err := c.IPv4Address(name); err == nil {
+		nic["ipv4_addresses"] = lxcFilterAddresses(ipv4s, includeLinkLocal)
+	}
+
+	nic["ipv6_addresses"] = []string{}
+	if ipv6s, err := c.IPv6Address(name); err == nil {
+		nic["ipv6_addresses"] = lxcFilterAddresses(ipv6s, includeLinkLocal)
+	}
+}
+
+// lxcReadAddresses sets the addresses of all interfaces of the container
+// except the loopback interface.
+func lxcReadAddresses(c *lxc.Container, d *schema.ResourceData) error {
+	includeLinkLocal := d.Get("include_link_local").(bool)
+	ipv4s := []string{}
+	ipv6s := []string{}
+	addresses := make(map[string]interface{})
+
+	if c.Running() {
+		interfaces, err := c.Interfaces()
+		if err != nil {
+			return err
+		}
+
+		for _, iface := range interfaces {
+			if iface == "lo" {
+				continue
+			}
+
+			ips, err := c.IPAddress(iface)
+			if err != nil {
+				continue
+			}
+
+			ips = lxcFilterAddresses(ips, includeLinkLocal)
+			for _, ip := range ips {
+				if net.ParseIP(ip).To4() != nil {
+					ipv4s = append(ipv4s, ip)
+				} else {
+					ipv6s = append(ipv6s, ip)
+				}
+			}
+			addresses[iface] = strings.Join(ips, ",")
+		}
+	}
+
+	if err := d.Set("ipv4_addresses", ipv4s); err != nil {
+		return err
+	}
+
+	if err := d.Set("ipv6_addresses", ipv6s); err != nil {
+		return err
+	}
+
+	if err := d.Set("addresses", addresses); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// lxcFilterAddresses removes link-local addresses unless they are
+// explicitly requested.
+func lxcFilterAddresses(addresses []string, includeLinkLocal bool) []string {
+	filtered := []string{}
+	for _, a := range addresses {
+		ip := net.ParseIP(a)
+		if ip == nil {
+			continue
+		}
+
+		if !includeLinkLocal && (ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()) {
+			continue
+		}
+
+		filtered = append(filtered, a)
+	}
+
+	return filtered
+}
+
+func lxcWaitForNetworkSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"timeout": &schema.Schema{
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      "2m",
+					ValidateFunc: lxcValidateDuration,
+				},
+				"family": &schema.Schema{
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      "ipv4",
+					ValidateFunc: lxcValidateAddressFamily,
+				},
+				"interface": &schema.Schema{
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+			},
+		},
+	}
+}
+
+// lxcWaitForNetwork waits for the container to get an address as
+// described by the wait_for_network attribute. Without it, the container
+// is given a few seconds to get any address. The wait never takes longer
+// than the given timeout of the operation.
+func lxcWaitForNetwork(c *lxc.Container, d *schema.ResourceData, operationTimeout time.Duration) error {
+	w := d.Get("wait_for_network").([]interface{})
+	if len(w) == 0 || w[0] == nil {
+		log.Printf("[INFO] Waiting container to startup networking...\n")
+		timeout := 5 * time.Second
+		if operationTimeout < timeout {
+			timeout = operationTimeout
+		}
+		c.WaitIPAddresses(timeout)
+		return nil
+	}
+
+	wait := w[0].(map[string]interface{})
+	timeout, _ := time.ParseDuration(wait["timeout"].(string))
+	if operationTimeout < timeout {
+		timeout = operationTimeout
+	}
+	family := wait["family"].(string)
+	iface := wait["interface"].(string)
+
+	description := fmt.Sprintf("an %s address", family)
+	if family == "any" {
+		description = "an address"
+	}
+	if iface != "" {
+		description = fmt.Sprintf("%s on %s", description, iface)
+	}
+
+	log.Printf("[INFO] Waiting for container %s to get %s", c.Name(), description)
+	err := resource.Retry(timeout, func() *resource.RetryError {
+		if lxcHasAddress(c, iface, family) {
+			return nil
+		}
+
+		return resource.RetryableError(fmt.Errorf("Container %s has no address yet", c.Name()))
+	})
+	if err != nil {
+		return fmt.Errorf("Container %s did not get %s within %s", c.Name(), description, timeout)
+	}
+
+	return nil
+}
+
+// lxcHasAddress reports whether the container has a routable address of
+// the given family, either on the given interface or on any interface.
+func lxcHasAddress(c *lxc.Container, iface, family string) bool {
+	var ipv4s, ipv6s []string
+	if iface != "" {
+		ipv4s, _ = c.IPv4Address(iface)
+		ipv6s, _ = c.IPv6Address(iface)
+	} else {
+		ipv4s, _ = c.IPv4Addresses()
+		ipv6s, _ = c.IPv6Addresses()
+	}
+
+	hasIPv4 := len(lxcFilterAddresses(ipv4s, false)) > 0
+	hasIPv6 := len(lxcFilterAddresses(ipv6s, false)) > 0
+
+	switch family {
+	case "ipv4":
+		return hasIPv4
+	case "ipv6":
+		return hasIPv6
+	}
+
+	return hasIPv4 || hasIPv6
+}
+
+func lxcValidateDuration(v interface{}, k string) (ws []string, errors []error) {
+	if _, err := time.ParseDuration(v.(string)); err != nil {
+		errors = append(errors, fmt.Errorf("%s must be a duration such as 30s or 5m", k))
+	}
+
+	return
+}
+
+func lxcValidateAddressFamily(v interface{}, k string) (ws []string, errors []error) {
+	switch v.(string) {
+	case "ipv4", "ipv6", "any":
+	default:
+		errors = append(errors, fmt.Errorf("%s must be one of ipv4, ipv6 or any", k))
+	}
+
+	return
+}
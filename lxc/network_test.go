@@ -0,0 +1,19 @@
+package lxc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLXCFilterAddresses(t *testing.T) {
+	addresses := []string{"10.0.3.15", "169.254.1.1", "fe80::216:3eff:fe00:1", "2001:db8::1"}
+
+	expected := []string{"10.0.3.15", "2001:db8::1"}
+	if actual := lxcFilterAddresses(addresses, false); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	if actual := lxcFilterAddresses(addresses, true); !reflect.DeepEqual(actual, addresses) {
+		t.Fatalf("Expected %#v, got %#v", addresses, actual)
+	}
+}
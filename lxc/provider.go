@@ -1,8 +1,11 @@
 package lxc
 
 import (
+	"log"
+
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
+	"gopkg.in/lxc/go-lxc.v2"
 )
 
 func Provider() terraform.ResourceProvider {
@@ -24,6 +27,7 @@ func Provider() terraform.ResourceProvider {
 			"lxc_bridge":    resourceLXCBridge(),
 			"lxc_clone":     resourceLXCClone(),
 			"lxc_container": resourceLXCContainer(),
+			"lxc_snapshot":  resourceLXCSnapshot(),
 		},
 
 		ConfigureFunc: configureProvider,
@@ -33,9 +37,12 @@ func Provider() terraform.ResourceProvider {
 func configureProvider(d *schema.ResourceData) (interface{}, error) {
 
 	config := Config{
-		LXCPath:    d.Get("lxc_path").(string),
-		LXCLogPath: d.Get("lxc_log_path").(string),
+		LXCPath:      d.Get("lxc_path").(string),
+		LXCLogPath:   d.Get("lxc_log_path").(string),
+		LegacyConfig: lxcLegacyConfig(lxc.Version()),
 	}
 
+	log.Printf("[INFO] Using liblxc %s", lxc.Version())
+
 	return &config, nil
 }
@@ -2,6 +2,8 @@ package lxc
 
 import (
 	"fmt"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/schema"
@@ -32,6 +34,21 @@ func testAccPreCheck(t *testing.T) {
 	// nothing to do yet
 }
 
+// testAccPreCheckBackend skips tests that need a storage backend which is
+// not set up on every host. LXC_TEST_BACKENDS lists the backends that are
+// available, separated by commas.
+func testAccPreCheckBackend(t *testing.T, backend string) {
+	testAccPreCheck(t)
+
+	for _, b := range strings.Split(os.Getenv("LXC_TEST_BACKENDS"), ",") {
+		if strings.TrimSpace(b) == backend {
+			return
+		}
+	}
+
+	t.Skipf("LXC_TEST_BACKENDS does not include %s", backend)
+}
+
 func testProviderConfig() (*Config, error) {
 	config := testAccProvider.Meta().(*Config)
 	if config == nil {
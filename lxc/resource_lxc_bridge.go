@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
 	"github.com/vishvananda/netlink"
 )
 
@@ -15,6 +16,11 @@ func resourceLXCBridge() *schema.Resource {
 		Read:   resourceLXCBridgeRead,
 		Update: nil,
 		Delete: resourceLXCBridgeDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceLXCBridgeImport,
+		},
+		SchemaVersion: 1,
+		MigrateState:  resourceLXCBridgeMigrateState,
 
 		Schema: map[string]*schema.Schema{
 			"name": &schema.Schema{
@@ -23,7 +29,7 @@ func resourceLXCBridge() *schema.Resource {
 				ForceNew: true,
 			},
 
-			"hostInterface": &schema.Schema{
+			"host_interface": &schema.Schema{
 				Type:     schema.TypeString,
 				Optional: true,
 				ForceNew: true,
@@ -50,7 +56,7 @@ func resourceLXCBridgeCreate(d *schema.ResourceData, meta interface{}) error {
 			return fmt.Errorf("Error creating bridge %s: %v", br, err)
 		}
 
-		if ifaceName, ok := d.GetOk("hostInterface"); ok {
+		if ifaceName, ok := d.GetOk("host_interface"); ok {
 			iface, err := netlink.LinkByName(ifaceName.(string))
 			if err != nil {
 				return fmt.Errorf("Error adding host interface %s to bridge %s : unknow host interface %v", ifaceName, br ,err)
@@ -86,6 +92,7 @@ func resourceLXCBridgeRead(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Unable to find bridge %v: %v", bridgeIndex, err)
 	}
 
+	d.Set("name", bridge.Attrs().Name)
 	d.Set("mac", bridge.Attrs().HardwareAddr.String())
 
 	log.Printf("[INFO] Bridge info: %v", bridge)
@@ -127,3 +134,39 @@ func resourceLXCBridgeDelete(d *schema.ResourceData, meta interface{}) error {
 
 	return nil
 }
+
+// resourceLXCBridgeImport accepts either the name or the index of a bridge.
+func resourceLXCBridgeImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	if _, err := strconv.Atoi(d.Id()); err == nil {
+		return []*schema.ResourceData{d}, nil
+	}
+
+	bridge, err := netlink.LinkByName(d.Id())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to find bridge %s: %v", d.Id(), err)
+	}
+
+	if bridge.Type() != "bridge" {
+		return nil, fmt.Errorf("Interface %s is not a bridge", d.Id())
+	}
+
+	d.SetId(strconv.Itoa(bridge.Attrs().Index))
+
+	return []*schema.ResourceData{d}, nil
+}
+
+// resourceLXCBridgeMigrateState renames hostInterface, which Terraform no
+// longer accepts as a field name, to host_interface.
+func resourceLXCBridgeMigrateState(v int, is *terraform.InstanceState, meta interface{}) (*terraform.InstanceState, error) {
+	if v > 0 || is.Empty() {
+		return is, nil
+	}
+
+	if iface, ok := is.Attributes["hostInterface"]; ok {
+		log.Printf("[INFO] Migrating hostInterface of bridge %s to host_interface", is.ID)
+		delete(is.Attributes, "hostInterface")
+		is.Attributes["host_interface"] = iface
+	}
+
+	return is, nil
+}
@@ -34,13 +34,32 @@ func TestLXCBridge(t *testing.T) {
 					resource.TestCheckResourceAttr(
 						"lxc_bridge.accept_test_iface", "name", "accept_test_iface"),
 					resource.TestCheckResourceAttr(
-						"lxc_bridge.accept_test_iface", "hostInterface", "accept_test"),
+						"lxc_bridge.accept_test_iface", "host_interface", "accept_test"),
 				),
 			},
 		},
 	})
 }
 
+func TestLXCBridgeMigrateState(t *testing.T) {
+	is := &terraform.InstanceState{
+		ID: "3",
+		Attributes: map[string]string{
+			"name":          "my_bridge",
+			"hostInterface": "eth1",
+		},
+	}
+
+	is, err := resourceLXCBridgeMigrateState(0, is, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if _, ok := is.Attributes["hostInterface"]; ok || is.Attributes["host_interface"] != "eth1" {
+		t.Fatalf("Unexpected attributes: %#v", is.Attributes)
+	}
+}
+
 func testAccCheckLXCBridgeExists(t *testing.T, n string, bridge *netlink.Link) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -96,5 +115,5 @@ var testAccLXCBridge = `
 var testAccLXCBridgeWithIface = `
 	resource "lxc_bridge" "accept_test" {
 		name = "accept_test_ip"
-		hostInterface = "accept_test"
+		host_interface = "accept_test"
 	}`
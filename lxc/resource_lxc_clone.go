@@ -3,8 +3,10 @@ package lxc
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/hashicorp/terraform/helper/customdiff"
 	"github.com/hashicorp/terraform/helper/schema"
 	"gopkg.in/lxc/go-lxc.v2"
 )
@@ -13,14 +15,25 @@ func resourceLXCClone() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceLXCCloneCreate,
 		Read:   resourceLXCCloneRead,
-		Update: nil,
+		Update: resourceLXCCloneUpdate,
 		Delete: resourceLXCCloneDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+		Importer: &schema.ResourceImporter{
+			State: resourceLXCCloneImport,
+		},
+		CustomizeDiff: customdiff.All(
+			lxcValidateAutostartOptions,
+			lxcValidateRename,
+		),
 
 		Schema: map[string]*schema.Schema{
 			"name": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
-				ForceNew: true,
 			},
 			"backend": &schema.Schema{
 				Type:     schema.TypeString,
@@ -49,31 +62,45 @@ func resourceLXCClone() *schema.Resource {
 				Type:     schema.TypeMap,
 				Optional: true,
 				Default:  nil,
-				ForceNew: true,
 			},
-			"network_interface": &schema.Schema{
-				Type:     schema.TypeList,
+			"limits":    lxcLimitsSchema(),
+			"autostart": lxcAutostartSchema(),
+			"mount":     lxcMountSchema(),
+			"file":      lxcFileSchema(),
+			"state": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "running",
+				ValidateFunc: lxcValidateState,
+			},
+			"shutdown_timeout": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      lxcDefaultShutdownTimeout,
+				ValidateFunc: lxcValidateDuration,
+			},
+			"source_shutdown_timeout": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      lxcDefaultShutdownTimeout,
+				ValidateFunc: lxcValidateDuration,
+			},
+			"keep_on_failure": &schema.Schema{
+				Type:     schema.TypeBool,
 				Optional: true,
-				ForceNew: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"type": &schema.Schema{
-							Type:     schema.TypeString,
-							Optional: true,
-							Default:  "veth",
-						},
-						"management": &schema.Schema{
-							Type:     schema.TypeString,
-							Optional: true,
-							Default:  false,
-						},
-						"options": &schema.Schema{
-							Type:     schema.TypeMap,
-							Optional: true,
-							Default:  nil,
-						},
-					},
-				},
+				Default:  false,
+			},
+			"adopt_existing": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"network_interface": lxcNetworkInterfaceSchema(),
+			"wait_for_network":  lxcWaitForNetworkSchema(),
+			"include_link_local": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
 			},
 
 			// exported
@@ -85,13 +112,29 @@ func resourceLXCClone() *schema.Resource {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			"ipv4_addresses": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"ipv6_addresses": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"addresses": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+			},
 		},
 	}
 }
 
-func resourceLXCCloneCreate(d *schema.ResourceData, meta interface{}) error {
-	var c *lxc.Container
+func resourceLXCCloneCreate(d *schema.ResourceData, meta interface{}) (err error) {
 	config := meta.(*Config)
+	deadline := time.Now().Add(d.Timeout(schema.TimeoutCreate))
+	handles := newLXCHandles(config)
+	defer handles.release()
 
 	backendType, err := lxcCheckBackend(d.Get("backend").(string))
 	if err != nil {
@@ -101,37 +144,56 @@ func resourceLXCCloneCreate(d *schema.ResourceData, meta interface{}) error {
 	name := d.Get("name").(string)
 	source := d.Get("source").(string)
 
-	c, err = lxc.NewContainer(name, config.LXCPath)
+	c, err := handles.get(name)
 	if err != nil {
 		return err
 	}
 
-	cl, err := lxc.NewContainer(source, config.LXCPath)
+	cl, err := handles.get(source)
 	if err != nil {
 		return err
 	}
 
-	// the source container must be stopped
-	log.Printf("[INFO] Stopping %s", source)
-	if cl.State() == lxc.RUNNING {
-		if err := cl.Stop(); err != nil {
+	adopt := c.Defined()
+	if adopt {
+		if err := lxcCheckAdopt(c, d); err != nil {
 			return err
 		}
-		if err := lxcWaitForState(c, config.LXCPath, []string{"RUNNING", "STOPPING"}, "STOPPED"); err != nil {
+
+		// an adopted container is never destroyed, so it is left
+		// out of the state if it can not be taken over.
+		log.Printf("[INFO] Adopting existing container %s\n", name)
+		defer func() {
+			if err != nil {
+				d.SetId("")
+			}
+		}()
+	} else {
+		// the source container must be stopped. It is a container of its
+		// own, so the shutdown_timeout of the clone does not apply.
+		sourceShutdownTimeout, _ := time.ParseDuration(d.Get("source_shutdown_timeout").(string))
+		if err := lxcStop(cl, sourceShutdownTimeout, deadline); err != nil {
 			return err
 		}
 
-	}
+		// from here on, a failure leaves a partially created container
+		// behind
+		defer func() {
+			if err != nil {
+				err = lxcRollback(c, d, err)
+			}
+		}()
 
-	log.Printf("[INFO] Cloning %s as %s", source, name)
-	err = cl.Clone(name, lxc.CloneOptions{
-		Backend:    backendType,
-		ConfigPath: config.LXCPath,
-		KeepMAC:    d.Get("keep_mac").(bool),
-		Snapshot:   d.Get("snapshot").(bool),
-	})
-	if err != nil {
-		return err
+		log.Printf("[INFO] Cloning %s as %s", source, name)
+		err = cl.Clone(name, lxc.CloneOptions{
+			Backend:    backendType,
+			ConfigPath: config.LXCPath,
+			KeepMAC:    d.Get("keep_mac").(bool),
+			Snapshot:   d.Get("snapshot").(bool),
+		})
+		if err != nil {
+			return err
+		}
 	}
 
 	d.SetId(c.Name())
@@ -140,62 +202,79 @@ func resourceLXCCloneCreate(d *schema.ResourceData, meta interface{}) error {
 		return err
 	}
 
-	// causes lxc to re-read the config file
-	c, err = lxc.NewContainer(name, config.LXCPath)
-	if err != nil {
-		return err
+	// the rootfs of an adopted container is already set up
+	if !adopt {
+		if err := lxcWriteFiles(c, d); err != nil {
+			return err
+		}
 	}
 
-	log.Printf("[INFO] Starting container %s\n", c.Name())
-	if err := c.Start(); err != nil {
-		return fmt.Errorf("Unable to start container: %s", err)
+	state := d.Get("state").(string)
+	if state == "stopped" {
+		log.Printf("[INFO] Leaving container %s stopped\n", c.Name())
+		if err := lxcStop(c, lxcShutdownTimeout(d), deadline); err != nil {
+			return err
+		}
+		return lxcReadContainer(c, d, config)
 	}
 
-	if err := lxcWaitForState(c, config.LXCPath, []string{"STOPPED", "STARTING"}, "RUNNING"); err != nil {
+	if err := lxcStartCreated(c, d, deadline); err != nil {
 		return err
 	}
 
-	log.Printf("[INFO] Waiting container to startup networking...\n")
-	c.WaitIPAddresses(5 * time.Second)
-
-	return resourceLXCCloneRead(d, meta)
-}
-
-func resourceLXCCloneRead(d *schema.ResourceData, meta interface{}) error {
-	config := meta.(*Config)
-
-	c, err := lxc.NewContainer(d.Id(), config.LXCPath)
-	if err != nil {
+	if err := lxcWaitForNetwork(c, d, lxcRemaining(deadline)); err != nil {
 		return err
 	}
 
-	if err = lxcIPAddressConfiguration(c, d); err != nil {
+	if err := lxcSetState(c, state, lxcShutdownTimeout(d), deadline); err != nil {
 		return err
 	}
 
-	return nil
+	return lxcReadContainer(c, d, config)
+}
+
+func resourceLXCCloneRead(d *schema.ResourceData, meta interface{}) error {
+	return lxcRefreshContainer(d, meta)
+}
+
+func resourceLXCCloneUpdate(d *schema.ResourceData, meta interface{}) error {
+	return lxcUpdateContainer(d, meta)
 }
 
 func resourceLXCCloneDelete(d *schema.ResourceData, meta interface{}) error {
+	return lxcDeleteContainer(d, meta)
+}
+
+// resourceLXCCloneImport imports a clone using an ID of the form
+// <source>/<name>, since the source of a clone is not recorded in its config.
+func resourceLXCCloneImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
 	config := meta.(*Config)
-	c, err := lxc.NewContainer(d.Id(), config.LXCPath)
-	if err != nil {
-		return err
+
+	parts := strings.SplitN(d.Id(), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Invalid import ID %s. Expected <source>/<name>.", d.Id())
 	}
+	source, name := parts[0], parts[1]
 
-	if c.State() == lxc.RUNNING {
-		if err := c.Stop(); err != nil {
-			return err
-		}
+	handles := newLXCHandles(config)
+	defer handles.release()
 
-		if err := lxcWaitForState(c, config.LXCPath, []string{"RUNNING", "STOPPING"}, "STOPPED"); err != nil {
-			return err
-		}
+	c, err := handles.get(name)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := c.Destroy(); err != nil {
-		return err
+	if !c.Defined() {
+		return nil, fmt.Errorf("Unable to find container %s", name)
+	}
+
+	d.SetId(c.Name())
+	d.Set("name", c.Name())
+	d.Set("source", source)
+
+	if err := lxcImportDefaults(d, resourceLXCClone().Schema); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return []*schema.ResourceData{d}, nil
 }
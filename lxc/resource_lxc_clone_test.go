@@ -30,6 +30,36 @@ func TestLXCClone(t *testing.T) {
 	})
 }
 
+func TestLXCClone_rename(t *testing.T) {
+	var container lxc.Container
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCCloneDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccLXCClone,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCCloneExists(
+						t, "lxc_clone.accept_clone", &container),
+				),
+			},
+			resource.TestStep{
+				Config: testAccLXCCloneRenamed,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCCloneExists(
+						t, "lxc_clone.accept_clone", &container),
+					testAccCheckLXCContainerNotDefined("accept_clone"),
+					resource.TestCheckResourceAttr(
+						"lxc_clone.accept_clone", "id", "accept_clone_renamed"),
+					resource.TestCheckResourceAttr(
+						"lxc_clone.accept_clone", "state", "running"),
+				),
+			},
+		},
+	})
+}
+
 func testAccCheckLXCCloneExists(t *testing.T, n string, container *lxc.Container) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -89,3 +119,13 @@ var testAccLXCClone = `
 		name = "accept_clone"
 		source = "${lxc_container.accept_test.name}"
 	}`
+
+var testAccLXCCloneRenamed = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+	}
+
+	resource "lxc_clone" "accept_clone" {
+		name = "accept_clone_renamed"
+		source = "${lxc_container.accept_test.name}"
+	}`
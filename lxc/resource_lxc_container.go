@@ -5,7 +5,7 @@ import (
 	"log"
 	"time"
 
-	"github.com/google/shlex"
+	"github.com/hashicorp/terraform/helper/customdiff"
 	"github.com/hashicorp/terraform/helper/schema"
 	"gopkg.in/lxc/go-lxc.v2"
 )
@@ -14,14 +14,26 @@ func resourceLXCContainer() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceLXCContainerCreate,
 		Read:   resourceLXCContainerRead,
-		Update: nil,
+		Update: resourceLXCContainerUpdate,
 		Delete: resourceLXCContainerDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+		Importer: &schema.ResourceImporter{
+			State: resourceLXCContainerImport,
+		},
+		CustomizeDiff: customdiff.All(
+			lxcValidateCloudInit,
+			lxcValidateAutostartOptions,
+			lxcValidateRename,
+		),
 
 		Schema: map[string]*schema.Schema{
 			"name": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
-				ForceNew: true,
 			},
 			"backend": &schema.Schema{
 				Type:     schema.TypeString,
@@ -99,36 +111,82 @@ func resourceLXCContainer() *schema.Resource {
 				Elem:     &schema.Schema{Type: schema.TypeString},
 				ForceNew: true,
 			},
-			"options": &schema.Schema{
-				Type:     schema.TypeMap,
-				Optional: true,
-				Default:  nil,
-				ForceNew: true,
-			},
-			"network_interface": &schema.Schema{
+			"restore_snapshot": &schema.Schema{
 				Type:     schema.TypeList,
 				Optional: true,
 				ForceNew: true,
+				MaxItems: 1,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
-						"type": &schema.Schema{
+						"container": &schema.Schema{
 							Type:     schema.TypeString,
-							Optional: true,
-							Default:  "veth",
+							Required: true,
 						},
-						"management": &schema.Schema{
+						"snapshot": &schema.Schema{
 							Type:     schema.TypeString,
-							Optional: true,
-							Default:  false,
-						},
-						"options": &schema.Schema{
-							Type:     schema.TypeMap,
-							Optional: true,
-							Default:  nil,
+							Required: true,
 						},
 					},
 				},
 			},
+			"user_data": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"meta_data": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"network_config": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"wait_for_cloud_init": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"options": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+				Default:  nil,
+			},
+			"limits":    lxcLimitsSchema(),
+			"autostart": lxcAutostartSchema(),
+			"mount":     lxcMountSchema(),
+			"file":      lxcFileSchema(),
+			"state": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "running",
+				ValidateFunc: lxcValidateState,
+			},
+			"shutdown_timeout": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      lxcDefaultShutdownTimeout,
+				ValidateFunc: lxcValidateDuration,
+			},
+			"keep_on_failure": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"adopt_existing": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"network_interface": lxcNetworkInterfaceSchema(),
+			"wait_for_network":  lxcWaitForNetworkSchema(),
+			"include_link_local": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
 
 			// exported
 			"address_v4": &schema.Schema{
@@ -139,19 +197,60 @@ func resourceLXCContainer() *schema.Resource {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			"ipv4_addresses": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"ipv6_addresses": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"addresses": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+			},
 			"exec": &schema.Schema{
 				Type:     schema.TypeList,
 				Optional: true,
 				Elem:     &schema.Schema{Type: schema.TypeString},
 				ForceNew: true,
 			},
+			"exec_env": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+				ForceNew: true,
+			},
+			"exec_uid": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				ForceNew: true,
+			},
+			"exec_gid": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				ForceNew: true,
+			},
+			"exec_cwd": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"exec_output": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
 		},
 	}
 }
 
-func resourceLXCContainerCreate(d *schema.ResourceData, meta interface{}) error {
-	var c *lxc.Container
+func resourceLXCContainerCreate(d *schema.ResourceData, meta interface{}) (err error) {
 	config := meta.(*Config)
+	deadline := time.Now().Add(d.Timeout(schema.TimeoutCreate))
+	handles := newLXCHandles(config)
+	defer handles.release()
 
 	backendType, err := lxcCheckBackend(d.Get("backend").(string))
 	if err != nil {
@@ -159,12 +258,140 @@ func resourceLXCContainerCreate(d *schema.ResourceData, meta interface{}) error
 	}
 
 	name := d.Get("name").(string)
-	c, err = lxc.NewContainer(name, config.LXCPath)
+	c, err := handles.get(name)
 	if err != nil {
 		return err
 	}
 
-	log.Printf("[INFO] Attempting to create container %s\n", c.Name())
+	adopt := c.Defined()
+	if adopt {
+		if err := lxcCheckAdopt(c, d); err != nil {
+			return err
+		}
+
+		// an adopted container is never destroyed, so it is left
+		// out of the state if it can not be taken over.
+		log.Printf("[INFO] Adopting existing container %s\n", name)
+		defer func() {
+			if err != nil {
+				d.SetId("")
+			}
+		}()
+	} else {
+		// from here on, a failure leaves a partially created container
+		// behind
+		defer func() {
+			if err != nil {
+				err = lxcRollback(c, d, err)
+			}
+		}()
+
+		if r := d.Get("restore_snapshot").([]interface{}); len(r) > 0 && r[0] != nil {
+			if err := lxcRestoreSnapshot(handles, r[0].(map[string]interface{}), name, d.Get("backend").(string)); err != nil {
+				return err
+			}
+		} else if err := lxcCreateFromTemplate(name, config, d, backendType, lxcRemaining(deadline)); err != nil {
+			return err
+		}
+	}
+
+	d.SetId(c.Name())
+
+	if err := lxcOptions(c, d, config); err != nil {
+		return err
+	}
+
+	// the rootfs of an adopted container is already set up
+	if !adopt {
+		if err := lxcWriteFiles(c, d); err != nil {
+			return err
+		}
+
+		if err := lxcWriteCloudInit(c, d); err != nil {
+			return err
+		}
+	}
+
+	// the container has to be started to run any commands in it, even
+	// if it should end up stopped.
+	state := d.Get("state").(string)
+	_, execDefined := d.GetOk("exec")
+	if state == "stopped" && !execDefined {
+		log.Printf("[INFO] Leaving container %s stopped\n", c.Name())
+		if err := lxcStop(c, lxcShutdownTimeout(d), deadline); err != nil {
+			return err
+		}
+		return lxcReadContainer(c, d, config)
+	}
+
+	if err := lxcStartCreated(c, d, deadline); err != nil {
+		return err
+	}
+
+	if err := lxcWaitForNetwork(c, d, lxcRemaining(deadline)); err != nil {
+		return err
+	}
+
+	if d.Get("wait_for_cloud_init").(bool) {
+		if err := lxcWaitForCloudInit(c, lxcRemaining(deadline)); err != nil {
+			return err
+		}
+	}
+
+	if execDefined {
+		outputs, err := lxcExecCommands(c, d)
+		d.Set("exec_output", outputs)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := lxcSetState(c, state, lxcShutdownTimeout(d), deadline); err != nil {
+		return err
+	}
+
+	return lxcReadContainer(c, d, config)
+}
+
+func resourceLXCContainerRead(d *schema.ResourceData, meta interface{}) error {
+	return lxcRefreshContainer(d, meta)
+}
+
+func resourceLXCContainerUpdate(d *schema.ResourceData, meta interface{}) error {
+	return lxcUpdateContainer(d, meta)
+}
+
+func resourceLXCContainerDelete(d *schema.ResourceData, meta interface{}) error {
+	return lxcDeleteContainer(d, meta)
+}
+
+func resourceLXCContainerImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	config := meta.(*Config)
+	handles := newLXCHandles(config)
+	defer handles.release()
+
+	c, err := handles.get(d.Id())
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.Defined() {
+		return nil, fmt.Errorf("Unable to find container %s", d.Id())
+	}
+
+	d.Set("name", c.Name())
+
+	if err := lxcImportDefaults(d, resourceLXCContainer().Schema); err != nil {
+		return nil, err
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+// lxcCreateFromTemplate creates a container with the template_* attributes
+// and gives up when the template runs longer than the timeout.
+func lxcCreateFromTemplate(name string, config *Config, d *schema.ResourceData, backendType lxc.BackendStore, timeout time.Duration) error {
+	log.Printf("[INFO] Attempting to create container %s\n", name)
 
 	var ea []string
 	for _, v := range d.Get("template_extra_args").([]interface{}) {
@@ -200,85 +427,67 @@ func resourceLXCContainerCreate(d *schema.ResourceData, meta interface{}) error
 		}
 	}
 
-	if err := c.Create(options); err != nil {
-		return err
+	finished, err := lxcRunWithTimeout(name, config.LXCPath, timeout, func(c *lxc.Container) error {
+		return c.Create(options)
+	})
+	if !finished && err == nil {
+		return &lxcTemplateTimeoutError{name, timeout}
 	}
 
-	d.SetId(c.Name())
-
-	if err := lxcOptions(c, d, config); err != nil {
-		return err
-	}
+	return err
+}
 
-	// causes lxc to re-read the config file
-	c, err = lxc.NewContainer(name, config.LXCPath)
-	if err != nil {
-		return err
-	}
+// lxcTemplateTimeoutError is returned when a template is still running
+// after the create timeout expired.
+type lxcTemplateTimeoutError struct {
+	name    string
+	timeout time.Duration
+}
 
-	log.Printf("[INFO] Starting container %s\n", c.Name())
-	if err := c.Start(); err != nil {
-		return fmt.Errorf("Unable to start container: %s", err)
-	}
+func (e *lxcTemplateTimeoutError) Error() string {
+	return fmt.Sprintf("Timed out after %s waiting for the template to create container %s", e.timeout, e.name)
+}
 
-	if err := lxcWaitForState(c, config.LXCPath, []string{"STOPPED", "STARTING"}, "RUNNING"); err != nil {
-		return err
-	}
+// lxcRestoreSnapshot creates a container from a snapshot of another
+// container. liblxc restores it with the backend of the source container,
+// which has to match the backend of the resource.
+func lxcRestoreSnapshot(handles *lxcHandles, restore map[string]interface{}, name, backend string) error {
+	source := restore["container"].(string)
+	snapName := restore["snapshot"].(string)
 
-	if commands, defined := d.GetOk("exec"); defined {
-		if defined {
-			for _, command := range commands.([]interface{}) {
-				args, err := shlex.Split(command.(string))
-				if( err != nil ){
-					log.Printf("[ERROR] Error parsing arguments for command %d, skipping to next command",command.(string))
-				}else{
-					log.Printf("[INFO] Running command in container %s : %s\n", c.Name(), command.(string))
-					c.RunCommand(args,lxc.DefaultAttachOptions)
-				}
-			}
-		}
+	// restoring a snapshot under the name of its own container replaces
+	// that container.
+	if source == name {
+		return fmt.Errorf("Unable to restore snapshot %s of container %s into the container itself", snapName, source)
 	}
 
-	log.Printf("[INFO] Waiting container to startup networking...\n")
-	c.WaitIPAddresses(5 * time.Second)
-
-	return resourceLXCContainerRead(d, meta)
-}
-
-func resourceLXCContainerRead(d *schema.ResourceData, meta interface{}) error {
-	config := meta.(*Config)
-
-	c, err := lxc.NewContainer(d.Id(), config.LXCPath)
+	src, err := handles.get(source)
 	if err != nil {
 		return err
 	}
 
-	if err = lxcIPAddressConfiguration(c, d); err != nil {
-		return err
+	if !src.Defined() {
+		return fmt.Errorf("Unable to find container %s", source)
 	}
 
-	return nil
-}
-
-func resourceLXCContainerDelete(d *schema.ResourceData, meta interface{}) error {
-	config := meta.(*Config)
-	c, err := lxc.NewContainer(d.Id(), config.LXCPath)
+	s, err := lxcFindSnapshot(src, snapName)
 	if err != nil {
 		return err
 	}
 
-	if c.State() == lxc.RUNNING {
-		if err := c.Stop(); err != nil {
-			return err
-		}
+	if s == nil {
+		return fmt.Errorf("Unable to find snapshot %s of container %s", snapName, source)
+	}
 
-		if err := lxcWaitForState(c, config.LXCPath, []string{"RUNNING", "STOPPING"}, "STOPPED"); err != nil {
-			return err
-		}
+	// a different backend would cause the container to be replaced on
+	// the next plan.
+	if actual := lxcReadBackend(src); backend != "best" && actual != "" && actual != backend {
+		return fmt.Errorf("Container %s uses the %s backend. Set backend to %s to restore its snapshots.", source, actual, actual)
 	}
 
-	if err := c.Destroy(); err != nil {
-		return err
+	log.Printf("[INFO] Restoring snapshot %s of container %s as %s", snapName, source, name)
+	if err := src.RestoreSnapshot(*s, name); err != nil {
+		return fmt.Errorf("Unable to restore snapshot %s of container %s: %s", snapName, source, err)
 	}
 
 	return nil
@@ -2,9 +2,11 @@ package lxc
 
 import (
 	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 
 	"gopkg.in/lxc/go-lxc.v2"
@@ -30,6 +32,349 @@ func TestLXCContainer(t *testing.T) {
 	})
 }
 
+func TestLXCContainer_update(t *testing.T) {
+	var container lxc.Container
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccLXCContainer,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_test", &container),
+				),
+			},
+			resource.TestStep{
+				Config: testAccLXCContainerOptions,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_test", &container),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "options.lxc.cgroup.memory.limit_in_bytes", "512M"),
+				),
+			},
+		},
+	})
+}
+
+func TestLXCContainer_state(t *testing.T) {
+	var container lxc.Container
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccLXCContainer,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_test", &container),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "state", "running"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccLXCContainerFrozen,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "state", "frozen"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccLXCContainerStopped,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "state", "stopped"),
+				),
+			},
+		},
+	})
+}
+
+func TestLXCContainer_disappears(t *testing.T) {
+	var container lxc.Container
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccLXCContainer,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_test", &container),
+					testAccCheckLXCContainerDisappears(&container),
+				),
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestLXCContainer_import(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccLXCContainerOptions,
+			},
+			resource.TestStep{
+				ResourceName:      "lxc_container.accept_test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestLXCContainer_rollback(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerNotDefined("accept_test"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config:      testAccLXCContainerFailingExec,
+				ExpectError: regexp.MustCompile("exited with status"),
+			},
+		},
+	})
+}
+
+func TestLXCContainer_adopt(t *testing.T) {
+	var container lxc.Container
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				PreConfig: func() { testAccCreateLXCContainer(t, "accept_test") },
+				Config:    testAccLXCContainerAdopt,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_test", &container),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "options.lxc.start.auto", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestLXCContainer_rename(t *testing.T) {
+	var container lxc.Container
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccLXCContainer,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_test", &container),
+				),
+			},
+			resource.TestStep{
+				Config: testAccLXCContainerRenamed,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_test", &container),
+					testAccCheckLXCContainerNotDefined("accept_test"),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "id", "accept_renamed"),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "state", "running"),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "options.lxc.start.auto", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestLXCContainer_renameStopped(t *testing.T) {
+	var container lxc.Container
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccLXCContainerStopped,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "state", "stopped"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccLXCContainerRenamedStopped,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerNotDefined("accept_test"),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "id", "accept_renamed"),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "state", "stopped"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccLXCContainerRenamed,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_test", &container),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "state", "running"),
+				),
+			},
+		},
+	})
+}
+
+func TestLXCContainer_waitForCloudInitStopped(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config:      testAccLXCContainerWaitForCloudInitStopped,
+				ExpectError: regexp.MustCompile("wait_for_cloud_init requires the container to be started"),
+			},
+		},
+	})
+}
+
+func TestLXCContainer_autostart(t *testing.T) {
+	var container lxc.Container
+	var pid int
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccLXCContainerAutostart,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_test", &container),
+					testAccCheckLXCContainerNotRestarted(&container, &pid),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "autostart.0.order", "1"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccLXCContainerAutostartUpdated,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_test", &container),
+					testAccCheckLXCContainerNotRestarted(&container, &pid),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "autostart.0.order", "2"),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "autostart.0.delay", "5"),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "autostart.0.groups.0", "web"),
+				),
+			},
+			resource.TestStep{
+				ResourceName:      "lxc_container.accept_test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestLXCContainer_autostartOptions(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config:      testAccLXCContainerAutostartConflict,
+				ExpectError: regexp.MustCompile("can not be set in options together with an autostart block"),
+			},
+			resource.TestStep{
+				Config: testAccLXCContainerAutostartOption,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "options.lxc.start.auto", "1"),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "autostart.#", "0"),
+				),
+			},
+			resource.TestStep{
+				ResourceName:      "lxc_container.accept_test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestLXCContainer_file(t *testing.T) {
+	var container lxc.Container
+	var pid int
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccLXCContainerFile,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_test", &container),
+					testAccCheckLXCContainerNotRestarted(&container, &pid),
+				),
+			},
+			resource.TestStep{
+				Config: testAccLXCContainerFileUpdated,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_test", &container),
+					testAccCheckLXCContainerNotRestarted(&container, &pid),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "file.0.content", "updated\n"),
+				),
+			},
+		},
+	})
+}
+
+func TestLXCImportDefaults(t *testing.T) {
+	resources := map[string]*schema.Resource{
+		"lxc_container": resourceLXCContainer(),
+		"lxc_clone":     resourceLXCClone(),
+		"lxc_snapshot":  resourceLXCSnapshot(),
+	}
+
+	for name, r := range resources {
+		d := r.TestResourceData()
+		d.SetId("accept_test")
+		if err := lxcImportDefaults(d, r.Schema); err != nil {
+			t.Fatalf("Unexpected error for %s: %s", name, err)
+		}
+
+		attributes := d.State().Attributes
+		for k, v := range r.Schema {
+			if v.Default == nil {
+				continue
+			}
+
+			if _, ok := attributes[k]; !ok {
+				t.Fatalf("Expected %s of %s to be set on import, got %#v", k, name, attributes)
+			}
+		}
+	}
+}
+
 func testAccCheckLXCContainerExists(t *testing.T, n string, container *lxc.Container) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -58,6 +403,30 @@ func testAccCheckLXCContainerExists(t *testing.T, n string, container *lxc.Conta
 	}
 }
 
+func testAccCheckLXCContainerDisappears(container *lxc.Container) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if err := container.Stop(); err != nil {
+			return err
+		}
+
+		return container.Destroy()
+	}
+}
+
+// testAccCheckLXCContainerNotRestarted checks that the init process of the
+// container is the same one as in the previous step.
+func testAccCheckLXCContainerNotRestarted(container *lxc.Container, pid *int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		current := container.InitPid()
+		if *pid != 0 && current != *pid {
+			return fmt.Errorf("Container was restarted: init pid changed from %d to %d", *pid, current)
+		}
+		*pid = current
+
+		return nil
+	}
+}
+
 func testAccCheckLXCContainerDestroy(s *terraform.State) error {
 	config, err := testProviderConfig()
 	if err != nil {
@@ -80,7 +449,165 @@ func testAccCheckLXCContainerDestroy(s *terraform.State) error {
 	return nil
 }
 
+func testAccCheckLXCContainerNotDefined(name string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		config, err := testProviderConfig()
+		if err != nil {
+			return err
+		}
+
+		c, err := lxc.NewContainer(name, config.LXCPath)
+		if err != nil {
+			return err
+		}
+		defer c.Release()
+
+		if c.Defined() {
+			return fmt.Errorf("Container %s was not cleaned up.", name)
+		}
+
+		return nil
+	}
+}
+
+// testAccCreateLXCContainer creates a container outside of Terraform.
+func testAccCreateLXCContainer(t *testing.T, name string) {
+	config, err := testProviderConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := lxc.NewContainer(name, config.LXCPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Release()
+
+	err = c.Create(lxc.TemplateOptions{
+		Template: "download",
+		Distro:   "ubuntu",
+		Release:  "trusty",
+		Arch:     "amd64",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 var testAccLXCContainer = `
 	resource "lxc_container" "accept_test" {
 		name = "accept_test"
 	}`
+
+var testAccLXCContainerOptions = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+		options {
+			lxc.cgroup.memory.limit_in_bytes = "512M"
+		}
+	}`
+
+var testAccLXCContainerFrozen = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+		state = "frozen"
+	}`
+
+var testAccLXCContainerStopped = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+		state = "stopped"
+	}`
+
+var testAccLXCContainerFailingExec = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+		exec = ["false"]
+	}`
+
+var testAccLXCContainerAdopt = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+		adopt_existing = true
+		options {
+			lxc.start.auto = "1"
+		}
+	}`
+
+var testAccLXCContainerRenamed = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_renamed"
+		options {
+			lxc.start.auto = "1"
+		}
+	}`
+
+var testAccLXCContainerRenamedStopped = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_renamed"
+		state = "stopped"
+	}`
+
+var testAccLXCContainerWaitForCloudInitStopped = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+		state = "stopped"
+		user_data = "#cloud-config\n"
+		wait_for_cloud_init = true
+	}`
+
+var testAccLXCContainerFile = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+		file {
+			path = "/etc/motd"
+			content = "created\n"
+		}
+	}`
+
+var testAccLXCContainerFileUpdated = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+		file {
+			path = "/etc/motd"
+			content = "updated\n"
+		}
+	}`
+
+var testAccLXCContainerAutostart = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+		autostart {
+			order = 1
+			groups = ["onboot"]
+		}
+	}`
+
+var testAccLXCContainerAutostartUpdated = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+		autostart {
+			order = 2
+			delay = 5
+			groups = ["web"]
+		}
+	}`
+
+var testAccLXCContainerAutostartConflict = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+		autostart {
+			order = 1
+		}
+		options {
+			lxc.start.auto = "1"
+		}
+	}`
+
+var testAccLXCContainerAutostartOption = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+		options {
+			lxc.start.auto = "1"
+		}
+	}`
@@ -0,0 +1,293 @@
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"gopkg.in/lxc/go-lxc.v2"
+)
+
+func resourceLXCSnapshot() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceLXCSnapshotCreate,
+		Read:   resourceLXCSnapshotRead,
+		Update: resourceLXCSnapshotUpdate,
+		Delete: resourceLXCSnapshotDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+		Importer: &schema.ResourceImporter{
+			State: resourceLXCSnapshotImport,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"container": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"comment": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"shutdown_timeout": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      lxcDefaultShutdownTimeout,
+				ValidateFunc: lxcValidateDuration,
+			},
+
+			// exported
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"timestamp": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"path": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceLXCSnapshotCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	deadline := time.Now().Add(d.Timeout(schema.TimeoutCreate))
+	name := d.Get("container").(string)
+
+	handles := newLXCHandles(config)
+	defer handles.release()
+
+	c, err := handles.get(name)
+	if err != nil {
+		return err
+	}
+
+	if !c.Defined() {
+		return fmt.Errorf("Unable to find container %s", name)
+	}
+
+	// liblxc only snapshots stopped containers. The container is put
+	// back into its previous state afterwards.
+	state, err := lxcStableState(c, deadline)
+	if err != nil {
+		return err
+	}
+
+	if state != "stopped" {
+		log.Printf("[INFO] Stopping container %s to snapshot it", name)
+		if err := lxcSetState(c, "stopped", lxcShutdownTimeout(d), deadline); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[INFO] Creating snapshot of container %s", name)
+	snapshot, snapErr := c.CreateSnapshot()
+
+	// the snapshot is recorded before the container is started again,
+	// so that it is not lost if that fails.
+	if snapErr == nil {
+		d.SetId(fmt.Sprintf("%s/%s", name, snapshot.Name))
+	}
+
+	if state != "stopped" {
+		if err := lxcSetState(c, state, lxcShutdownTimeout(d), deadline); err != nil {
+			return err
+		}
+	}
+
+	if snapErr != nil {
+		return fmt.Errorf("Unable to snapshot container %s: %s", name, snapErr)
+	}
+
+	if comment := d.Get("comment").(string); comment != "" {
+		s, err := lxcFindSnapshot(c, snapshot.Name)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return fmt.Errorf("Unable to find snapshot %s of container %s", snapshot.Name, name)
+		}
+
+		if err := ioutil.WriteFile(s.CommentPath, []byte(comment+"\n"), 0644); err != nil {
+			return err
+		}
+	}
+
+	return resourceLXCSnapshotRead(d, meta)
+}
+
+func resourceLXCSnapshotRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	name, snapName, err := lxcParseSnapshotID(d.Id())
+	if err != nil {
+		return err
+	}
+
+	handles := newLXCHandles(config)
+	defer handles.release()
+
+	c, err := handles.get(name)
+	if err != nil {
+		return err
+	}
+
+	if !c.Defined() {
+		log.Printf("[WARN] Container %s not found, removing snapshot %s from state", name, snapName)
+		d.SetId("")
+		return nil
+	}
+
+	s, err := lxcFindSnapshot(c, snapName)
+	if err != nil {
+		return err
+	}
+
+	if s == nil {
+		log.Printf("[WARN] Snapshot %s of container %s not found, removing from state", snapName, name)
+		d.SetId("")
+		return nil
+	}
+
+	d.Set("container", name)
+	d.Set("name", s.Name)
+	d.Set("timestamp", s.Timestamp)
+	d.Set("path", filepath.Join(s.Path, s.Name))
+
+	comment, err := ioutil.ReadFile(s.CommentPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	d.Set("comment", strings.TrimSuffix(string(comment), "\n"))
+
+	return nil
+}
+
+// resourceLXCSnapshotUpdate only records a new shutdown_timeout. A
+// snapshot is never taken again, so the value only applies when a change
+// to container or comment replaces the snapshot.
+func resourceLXCSnapshotUpdate(d *schema.ResourceData, meta interface{}) error {
+	return resourceLXCSnapshotRead(d, meta)
+}
+
+func resourceLXCSnapshotDelete(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	name, snapName, err := lxcParseSnapshotID(d.Id())
+	if err != nil {
+		return err
+	}
+
+	handles := newLXCHandles(config)
+	defer handles.release()
+
+	c, err := handles.get(name)
+	if err != nil {
+		return err
+	}
+
+	if !c.Defined() {
+		log.Printf("[INFO] Container %s already deleted", name)
+		return nil
+	}
+
+	s, err := lxcFindSnapshot(c, snapName)
+	if err != nil {
+		return err
+	}
+
+	if s == nil {
+		log.Printf("[INFO] Snapshot %s of container %s already deleted", snapName, name)
+		return nil
+	}
+
+	log.Printf("[INFO] Destroying snapshot %s of container %s", snapName, name)
+	if err := lxcDestroySnapshot(config, name, *s, d.Timeout(schema.TimeoutDelete)); err != nil {
+		return fmt.Errorf("Unable to destroy snapshot %s of container %s: %s", snapName, name, err)
+	}
+
+	return nil
+}
+
+// lxcDestroySnapshot destroys a snapshot and gives up when that takes
+// longer than the timeout.
+func lxcDestroySnapshot(config *Config, name string, s lxc.Snapshot, timeout time.Duration) error {
+	finished, err := lxcRunWithTimeout(name, config.LXCPath, timeout, func(c *lxc.Container) error {
+		return c.DestroySnapshot(s)
+	})
+	if !finished && err == nil {
+		return fmt.Errorf("timeout after %s. It is still being removed in the background.", timeout)
+	}
+
+	return err
+}
+
+// resourceLXCSnapshotImport imports a snapshot using an ID of the form
+// <container>/<snapshot>.
+func resourceLXCSnapshotImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	if _, _, err := lxcParseSnapshotID(d.Id()); err != nil {
+		return nil, err
+	}
+
+	if err := lxcImportDefaults(d, resourceLXCSnapshot().Schema); err != nil {
+		return nil, err
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func lxcParseSnapshotID(id string) (string, string, error) {
+	parts := strings.SplitN(id, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("Invalid snapshot ID %s. Expected <container>/<snapshot>.", id)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// lxcFindSnapshot returns the snapshot of a container with the given name,
+// or nil if there is no such snapshot.
+func lxcFindSnapshot(c *lxc.Container, name string) (*lxc.Snapshot, error) {
+	snapshots, err := c.Snapshots()
+	if err != nil {
+		// liblxc reports a container without snapshots as an error
+		if err == lxc.ErrNoSnapshot {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	for i := range snapshots {
+		if snapshots[i].Name == name {
+			return &snapshots[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// lxcHasSnapshots reports whether a container has any snapshots.
+func lxcHasSnapshots(c *lxc.Container) (bool, error) {
+	snapshots, err := c.Snapshots()
+	if err != nil {
+		if err == lxc.ErrNoSnapshot {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return len(snapshots) > 0, nil
+}
@@ -0,0 +1,225 @@
+package lxc
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+
+	"gopkg.in/lxc/go-lxc.v2"
+)
+
+func TestLXCSnapshot(t *testing.T) {
+	var snapshot lxc.Snapshot
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCSnapshotDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccLXCSnapshot,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCSnapshotExists(
+						t, "lxc_snapshot.accept_snapshot", &snapshot),
+					resource.TestCheckResourceAttr(
+						"lxc_snapshot.accept_snapshot", "comment", "before upgrade"),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_test", "state", "running"),
+				),
+			},
+		},
+	})
+}
+
+func TestLXCSnapshot_rename(t *testing.T) {
+	var snapshot lxc.Snapshot
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCSnapshotDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccLXCSnapshot,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCSnapshotExists(
+						t, "lxc_snapshot.accept_snapshot", &snapshot),
+				),
+			},
+			resource.TestStep{
+				Config:      testAccLXCSnapshotRenamed,
+				ExpectError: regexp.MustCompile("it has snapshots"),
+			},
+		},
+	})
+}
+
+func TestLXCSnapshot_restore(t *testing.T) {
+	var container lxc.Container
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccLXCSnapshotRestore,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_restore", &container),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_restore", "name", "accept_restore"),
+				),
+			},
+		},
+	})
+}
+
+func TestLXCSnapshot_restoreBackend(t *testing.T) {
+	var container lxc.Container
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheckBackend(t, "btrfs") },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckLXCContainerDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config:      fmt.Sprintf(testAccLXCSnapshotRestoreBackend, "directory"),
+				ExpectError: regexp.MustCompile("uses the btrfs backend"),
+			},
+			resource.TestStep{
+				Config: fmt.Sprintf(testAccLXCSnapshotRestoreBackend, "btrfs"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckLXCContainerExists(
+						t, "lxc_container.accept_restore", &container),
+					resource.TestCheckResourceAttr(
+						"lxc_container.accept_restore", "backend", "btrfs"),
+				),
+			},
+		},
+	})
+}
+
+func testAccCheckLXCSnapshotExists(t *testing.T, n string, snapshot *lxc.Snapshot) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %v", n)
+		}
+
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("No ID is set")
+		}
+
+		config, err := testProviderConfig()
+		if err != nil {
+			return err
+		}
+
+		c, err := lxc.NewContainer(rs.Primary.Attributes["container"], config.LXCPath)
+		if err != nil {
+			return err
+		}
+
+		found, err := lxcFindSnapshot(c, rs.Primary.Attributes["name"])
+		if err != nil {
+			return err
+		}
+
+		if found == nil {
+			return fmt.Errorf("Unable to find snapshot %s.", rs.Primary.ID)
+		}
+
+		*snapshot = *found
+
+		return nil
+	}
+}
+
+func testAccCheckLXCSnapshotDestroy(s *terraform.State) error {
+	config, err := testProviderConfig()
+	if err != nil {
+		return err
+	}
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "lxc_snapshot" {
+			continue
+		}
+
+		c, err := lxc.NewContainer(rs.Primary.Attributes["container"], config.LXCPath)
+		if err != nil {
+			return err
+		}
+
+		if !c.Defined() {
+			continue
+		}
+
+		found, err := lxcFindSnapshot(c, rs.Primary.Attributes["name"])
+		if err != nil {
+			return err
+		}
+
+		if found != nil {
+			return fmt.Errorf("Snapshot still exists.")
+		}
+	}
+
+	return nil
+}
+
+var testAccLXCSnapshot = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+	}
+
+	resource "lxc_snapshot" "accept_snapshot" {
+		container = "${lxc_container.accept_test.name}"
+		comment = "before upgrade"
+	}`
+
+var testAccLXCSnapshotRenamed = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_renamed"
+	}
+
+	resource "lxc_snapshot" "accept_snapshot" {
+		container = "${lxc_container.accept_test.name}"
+		comment = "before upgrade"
+	}`
+
+var testAccLXCSnapshotRestore = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+	}
+
+	resource "lxc_snapshot" "accept_snapshot" {
+		container = "${lxc_container.accept_test.name}"
+	}
+
+	resource "lxc_container" "accept_restore" {
+		name = "accept_restore"
+		restore_snapshot {
+			container = "${lxc_snapshot.accept_snapshot.container}"
+			snapshot = "${lxc_snapshot.accept_snapshot.name}"
+		}
+	}`
+
+var testAccLXCSnapshotRestoreBackend = `
+	resource "lxc_container" "accept_test" {
+		name = "accept_test"
+		backend = "btrfs"
+	}
+
+	resource "lxc_snapshot" "accept_snapshot" {
+		container = "${lxc_container.accept_test.name}"
+	}
+
+	resource "lxc_container" "accept_restore" {
+		name = "accept_restore"
+		backend = "%s"
+		restore_snapshot {
+			container = "${lxc_snapshot.accept_snapshot.container}"
+			snapshot = "${lxc_snapshot.accept_snapshot.name}"
+		}
+	}`
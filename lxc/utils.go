@@ -2,96 +2,997 @@ package lxc
 
 import (
 	"fmt"
-	"io/ioutil"
 	"log"
-	"strconv"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/jtopjian/terraform-provider-lxc/lxcconfig"
 	"gopkg.in/lxc/go-lxc.v2"
 )
 
-func lxcContainerStateRefreshFunc(name, lxcpath string) resource.StateRefreshFunc {
-	return func() (interface{}, string, error) {
-		c, err := lxc.NewContainer(name, lxcpath)
-		if err != nil {
-			return c, "", err
-		}
-		state := c.State()
-		return c, fmt.Sprintf("%s", state), nil
+// lxcWaitForState waits for the container to reach the given state.
+// liblxc is notified of state changes by the container's monitor, so the
+// wait ends as soon as the state is reached.
+func lxcWaitForState(c *lxc.Container, state lxc.State, timeout time.Duration) error {
+	if !c.Wait(state, timeout) {
+		return fmt.Errorf("Error waiting for container (%s) to change to state (%s): timeout after %s", c.Name(), state, timeout)
 	}
-}
 
-func lxcWaitForState(c *lxc.Container, LXCPath string, pendingStates []string, targetState string) error {
-	stateConf := &resource.StateChangeConf{
-		Pending:    pendingStates,
-		Target:     targetState,
-		Refresh:    lxcContainerStateRefreshFunc(c.Name(), LXCPath),
-		Timeout:    10 * time.Minute,
-		Delay:      5 * time.Second,
-		MinTimeout: 1 * time.Second,
-	}
+	return nil
+}
 
-	_, err := stateConf.WaitForState()
-	if err != nil {
-		return fmt.Errorf("Error waiting for container (%s) to change to state (%s): %s", c.Name(), targetState, err)
+// lxcRemaining returns the time that is left until the deadline of an
+// operation. It is never negative, since liblxc waits forever for a
+// negative timeout.
+func lxcRemaining(deadline time.Time) time.Duration {
+	if remaining := time.Until(deadline); remaining > 0 {
+		return remaining
 	}
 
-	return nil
+	return 0
 }
 
 func lxcOptions(c *lxc.Container, d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
-	var options []string
-	optionsFound := false
-	includeFound := false
 	configFile := config.LXCPath + "/" + c.Name() + "/config"
 	customConfigFile := config.LXCPath + "/" + c.Name() + "/config_tf"
-	includeLine := fmt.Sprintf("lxc.include = %s", customConfigFile)
+
+	// indexed network keys have to continue after the interfaces of the
+	// container's own config.
+	offset, err := lxcNetworkOffset(c)
+	if err != nil {
+		return err
+	}
+
+	custom := lxcconfig.New()
+	custom.AddComment("Managed by Terraform. Changes to this file will be overwritten.")
 
 	networkInterfaces := d.Get("network_interface").([]interface{})
-	for _, n := range networkInterfaces {
+	for i, n := range networkInterfaces {
 		nic := n.(map[string]interface{})
-		options = append(options, fmt.Sprintf("lxc.network.type = %s", nic["type"]))
-		for k, v := range nic["options"].(map[string]interface{}) {
-			options = append(options, fmt.Sprintf("lxc.network.%s = %s", k, v.(string)))
+		nicOptions := nic["options"].(map[string]interface{})
+		if config.LegacyConfig {
+			custom.Add("lxc.network.type", nic["type"].(string))
+			if name := nic["name"].(string); name != "" {
+				custom.Add("lxc.network.name", name)
+			}
+			for _, k := range lxcSortedKeys(nicOptions) {
+				custom.Add("lxc.network."+k, nicOptions[k].(string))
+			}
+			continue
+		}
+
+		custom.Add(lxcNetworkKey(false, offset+i, "type"), nic["type"].(string))
+		if name := nic["name"].(string); name != "" {
+			custom.Add(lxcNetworkKey(false, offset+i, "name"), name)
+		}
+		for _, k := range lxcSortedKeys(nicOptions) {
+			custom.Add(lxcNetworkKey(false, offset+i, lxcTranslateNetworkKey(k)), nicOptions[k].(string))
 		}
 	}
 
 	containerOptions := d.Get("options").(map[string]interface{})
-	if containerOptions != nil {
-		optionsFound = true
-		for k, v := range containerOptions {
-			options = append(options, fmt.Sprintf("%s = %s", k, v.(string)))
+	for _, k := range lxcSortedKeys(containerOptions) {
+		key := k
+		if !config.LegacyConfig {
+			key = lxcTranslateKey(k, offset+len(networkInterfaces)-1)
 		}
+		custom.Add(key, containerOptions[k].(string))
+	}
+
+	for _, entry := range lxcMountEntries(d.Get("mount").([]interface{})) {
+		custom.Add("lxc.mount.entry", entry)
+	}
+
+	// the keys of the limits and autostart blocks are marked, so that
+	// they can be told apart from the same keys in options when they are
+	// read back.
+	limits, err := lxcLimitsOptions(d.Get("limits").([]interface{}))
+	if err != nil {
+		return err
+	}
+	if len(limits) > 0 {
+		custom.AddComment(lxcLimitsSection)
+	}
+	for _, k := range lxcSortedKeys(limits) {
+		custom.Add(k, limits[k])
 	}
 
-	configFileContents, err := ioutil.ReadFile(configFile)
+	lxcAutostartConfig(d.Get("autostart").([]interface{}), custom)
+
+	// the custom config file is always rewritten as a whole, so settings
+	// which were removed from the resource do not linger.
+	log.Printf("[DEBUG] Writing %s:\n%s", customConfigFile, custom.Bytes())
+	if err := custom.WriteFile(customConfigFile, 0640); err != nil {
+		return err
+	}
+
+	mainConfig, err := lxcconfig.ParseFile(configFile)
 	if err != nil {
 		return err
 	}
 
-	if optionsFound == true {
-		lines := strings.Split(string(configFileContents), "\n")
-		for _, line := range lines {
-			if line == includeLine {
-				includeFound = true
+	if lxcEnsureInclude(mainConfig, customConfigFile) {
+		if err := mainConfig.WriteFile(configFile, 0640); err != nil {
+			return err
+		}
+	}
+
+	return lxcReloadConfig(c)
+}
+
+// lxcEnsureInclude makes sure that a config includes the custom config
+// file exactly once. Includes of custom config files at other paths are
+// stale and removed. It reports whether the config was changed.
+func lxcEnsureInclude(mainConfig *lxcconfig.File, customConfigFile string) bool {
+	count := 0
+	changed := false
+	for _, include := range mainConfig.Includes() {
+		switch {
+		case include == customConfigFile:
+			count++
+		case filepath.Base(include) == "config_tf":
+			mainConfig.DeleteEntry("lxc.include", include)
+			changed = true
+		}
+	}
+
+	if count != 1 {
+		mainConfig.DeleteEntry("lxc.include", customConfigFile)
+		mainConfig.Add("lxc.include", customConfigFile)
+		changed = true
+	}
+
+	return changed
+}
+
+// The sections of the custom config file that hold the entries of the
+// limits and autostart blocks.
+const (
+	lxcLimitsSection    = "limits"
+	lxcAutostartSection = "autostart"
+)
+
+// lxcConfigSections splits the custom config file into the sections that
+// lxcOptions starts with a comment such as "# limits". Entries outside of
+// such a section are returned under "".
+func lxcConfigSections(f *lxcconfig.File) map[string]*lxcconfig.File {
+	sections := map[string]*lxcconfig.File{
+		"":                  lxcconfig.New(),
+		lxcLimitsSection:    lxcconfig.New(),
+		lxcAutostartSection: lxcconfig.New(),
+	}
+
+	section := ""
+	for _, line := range f.Lines {
+		if line.IsComment() {
+			name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line.String()), "#"))
+			if _, ok := sections[name]; ok {
+				section = name
 			}
+			continue
+		}
+
+		if line.Key != "" {
+			sections[section].Add(line.Key, line.Value)
 		}
+	}
 
-		// if the lxc.include line was not found, add it.
-		if includeFound == false {
-			lines = append(lines, includeLine, "\n")
-			if err := ioutil.WriteFile(configFile, []byte(strings.Join(lines, "\n")), 0640); err != nil {
+	return sections
+}
+
+// lxcSortedKeys returns the keys of a map in order, so that config files
+// are written the same way every time.
+func lxcSortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]interface{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]string:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// lxcUpdateConfig rewrites the custom config file and brings a running
+// container in line with it. Changed cgroup options are applied to the
+// running container directly. The autostart settings are only used by
+// lxc-autostart and need no restart. Any other change requires a restart.
+func lxcUpdateConfig(c *lxc.Container, d *schema.ResourceData, meta interface{}, deadline time.Time) error {
+	config := meta.(*Config)
+
+	if err := lxcOptions(c, d, config); err != nil {
+		return err
+	}
+
+	if c.State() != lxc.RUNNING {
+		return nil
+	}
+
+	// mount entries are only set up when the container starts
+	restart := d.HasChange("network_interface") || d.HasChange("mount")
+	cgroupItems := make(map[string]string)
+
+	o, n := d.GetChange("limits")
+	oldOptions, err := lxcLimitsOptions(o.([]interface{}))
+	if err != nil {
+		return err
+	}
+	newOptions, err := lxcLimitsOptions(n.([]interface{}))
+	if err != nil {
+		return err
+	}
+
+	o, n = d.GetChange("options")
+	for k, v := range o.(map[string]interface{}) {
+		oldOptions[k] = v.(string)
+	}
+	for k, v := range n.(map[string]interface{}) {
+		newOptions[k] = v.(string)
+	}
+
+	for k, v := range oldOptions {
+		if _, ok := newOptions[k]; !ok {
+			log.Printf("[DEBUG] Option %s was removed", k)
+			restart = true
+		} else if newOptions[k] != v && !lxcIsCgroupKey(k) {
+			log.Printf("[DEBUG] Option %s was changed", k)
+			restart = true
+		}
+	}
+	for k, v := range newOptions {
+		if oldOptions[k] == v {
+			continue
+		}
+		if lxcIsCgroupKey(k) {
+			cgroupItems[k] = v
+		} else {
+			log.Printf("[DEBUG] Option %s was added", k)
+			restart = true
+		}
+	}
+
+	if restart {
+		return lxcRestart(c, lxcShutdownTimeout(d), deadline)
+	}
+
+	for k, v := range cgroupItems {
+		item := strings.TrimPrefix(strings.TrimPrefix(k, "lxc.cgroup2."), "lxc.cgroup.")
+		log.Printf("[INFO] Setting cgroup item %s to %s on container %s", item, v, c.Name())
+		if err := c.SetCgroupItem(item, v); err != nil {
+			return fmt.Errorf("Unable to set cgroup item %s on container %s: %s", item, c.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// lxcValidateRename rejects a new name for a container that can not be
+// renamed, before anything is changed.
+func lxcValidateRename(d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" || !d.HasChange("name") {
+		return nil
+	}
+
+	handles := newLXCHandles(meta.(*Config))
+	defer handles.release()
+
+	c, err := handles.get(d.Id())
+	if err != nil {
+		return err
+	}
+
+	// a container that is gone is removed from the state when it is read
+	if !c.Defined() {
+		return nil
+	}
+
+	return lxcCheckRename(c)
+}
+
+// lxcCheckRename checks that liblxc is able to rename a container. liblxc
+// does not move the snapshots of a container and refuses to rename a
+// container that has any.
+func lxcCheckRename(c *lxc.Container) error {
+	hasSnapshots, err := lxcHasSnapshots(c)
+	if err != nil {
+		return err
+	}
+
+	if hasSnapshots {
+		return fmt.Errorf("Unable to rename container %s: it has snapshots, which liblxc can not rename. Destroy its snapshots before renaming it", c.Name())
+	}
+
+	return nil
+}
+
+// lxcRename renames a stopped or running container in place and returns
+// the handle of the renamed container. The container is left stopped, so
+// that the caller can apply config changes before it is started again.
+func lxcRename(handles *lxcHandles, c *lxc.Container, d *schema.ResourceData, meta interface{}, deadline time.Time) (*lxc.Container, error) {
+	config := meta.(*Config)
+	oldName := c.Name()
+	newName := d.Get("name").(string)
+
+	n, err := handles.get(newName)
+	if err != nil {
+		return nil, err
+	}
+	defined := n.Defined()
+	handles.forget(newName)
+
+	if defined {
+		return nil, fmt.Errorf("Unable to rename container %s: container %s already exists", oldName, newName)
+	}
+
+	if err := lxcCheckRename(c); err != nil {
+		return nil, err
+	}
+
+	// liblxc only renames stopped containers
+	if err := lxcStop(c, lxcShutdownTimeout(d), deadline); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Renaming container %s to %s", oldName, newName)
+	if err := c.Rename(newName); err != nil {
+		return nil, fmt.Errorf("Unable to rename container %s to %s: %s", oldName, newName, err)
+	}
+	handles.forget(oldName)
+	d.SetId(newName)
+
+	// the custom config file moved with the container, but the config
+	// still includes it from the old directory. liblxc does not load a
+	// config with a missing include, so this is fixed before the
+	// container is opened again.
+	configFile := config.LXCPath + "/" + newName + "/config"
+	mainConfig, err := lxcconfig.ParseFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if lxcEnsureInclude(mainConfig, config.LXCPath+"/"+newName+"/config_tf") {
+		if err := mainConfig.WriteFile(configFile, 0640); err != nil {
+			return nil, err
+		}
+	}
+
+	return handles.get(newName)
+}
+
+// lxcRestart stops a running container and starts it again so that
+// changes to its config file take effect.
+func lxcRestart(c *lxc.Container, shutdownTimeout time.Duration, deadline time.Time) error {
+	if err := lxcStop(c, shutdownTimeout, deadline); err != nil {
+		return err
+	}
+
+	return lxcStart(c, deadline)
+}
+
+// lxcStop stops a running or frozen container. With a shutdown timeout,
+// the container is asked to shut down cleanly first and is only killed
+// if it is still running when the timeout expires. The shutdown never
+// takes longer than the deadline of the operation.
+func lxcStop(c *lxc.Container, shutdownTimeout time.Duration, deadline time.Time) error {
+	if c.State() == lxc.STOPPED {
+		return nil
+	}
+
+	if remaining := lxcRemaining(deadline); remaining < shutdownTimeout {
+		shutdownTimeout = remaining
+	}
+
+	if shutdownTimeout > 0 {
+		// a frozen container can not react to the shutdown request
+		if c.State() == lxc.FROZEN {
+			log.Printf("[INFO] Unfreezing container %s to shut it down\n", c.Name())
+			if err := c.Unfreeze(); err != nil {
+				return fmt.Errorf("Unable to unfreeze container: %s", err)
+			}
+		}
+
+		log.Printf("[INFO] Shutting down container %s\n", c.Name())
+		err := c.Shutdown(shutdownTimeout)
+		if err == nil {
+			return lxcWaitForState(c, lxc.STOPPED, lxcRemaining(deadline))
+		}
+
+		log.Printf("[WARN] Container %s did not shut down within %s: %s", c.Name(), shutdownTimeout, err)
+	}
+
+	log.Printf("[INFO] Stopping container %s\n", c.Name())
+	if err := c.Stop(); err != nil {
+		return err
+	}
+
+	return lxcWaitForState(c, lxc.STOPPED, lxcRemaining(deadline))
+}
+
+// lxcRollback cleans up after a failed creation of a container and
+// returns the error that caused it. The container is destroyed and
+// removed from the state, unless keep_on_failure is set. A kept container
+// stays in the state, so Terraform marks it as tainted and replaces it on
+// the next apply.
+func lxcRollback(c *lxc.Container, d *schema.ResourceData, cause error) error {
+	if _, ok := cause.(*lxcTemplateTimeoutError); ok {
+		// the template is still running and the container can not be
+		// destroyed yet.
+		d.SetId(c.Name())
+		return cause
+	}
+
+	if !c.Defined() {
+		d.SetId("")
+		return cause
+	}
+
+	if d.Get("keep_on_failure").(bool) {
+		log.Printf("[WARN] Keeping container %s after its creation failed", c.Name())
+		d.SetId(c.Name())
+		return cause
+	}
+
+	log.Printf("[INFO] Destroying container %s after its creation failed", c.Name())
+	if err := lxcStop(c, 0, time.Now().Add(d.Timeout(schema.TimeoutDelete))); err != nil {
+		d.SetId(c.Name())
+		return fmt.Errorf("%s. Unable to stop container %s to clean it up: %s", cause, c.Name(), err)
+	}
+
+	if err := c.Destroy(); err != nil {
+		d.SetId(c.Name())
+		return fmt.Errorf("%s. Unable to destroy container %s to clean it up: %s", cause, c.Name(), err)
+	}
+
+	d.SetId("")
+	return cause
+}
+
+// lxcStartCreated starts a container that was just created. An adopted
+// container that is already running is restarted instead, so that the
+// config written by the provider takes effect.
+func lxcStartCreated(c *lxc.Container, d *schema.ResourceData, deadline time.Time) error {
+	if c.State() != lxc.STOPPED {
+		log.Printf("[INFO] Restarting container %s to apply its config\n", c.Name())
+		return lxcRestart(c, lxcShutdownTimeout(d), deadline)
+	}
+
+	log.Printf("[INFO] Starting container %s\n", c.Name())
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("Unable to start container: %s", err)
+	}
+
+	return lxcWaitForState(c, lxc.RUNNING, lxcRemaining(deadline))
+}
+
+// lxcCheckAdopt checks whether an existing container may be taken over
+// by a resource that would otherwise create it.
+func lxcCheckAdopt(c *lxc.Container, d *schema.ResourceData) error {
+	if !d.Get("adopt_existing").(bool) {
+		return fmt.Errorf("Container %s already exists. Set adopt_existing to take it over.", c.Name())
+	}
+
+	// a different backend would cause the container to be replaced on
+	// the next plan.
+	backend := d.Get("backend").(string)
+	if actual := lxcReadBackend(c); backend != "best" && actual != "" && actual != backend {
+		return fmt.Errorf("Container %s uses the %s backend. Set backend to %s to adopt it.", c.Name(), actual, actual)
+	}
+
+	return nil
+}
+
+// lxcDefaultShutdownTimeout is how long a container is given to shut down
+// cleanly before it is killed. It is the same default as lxc-stop's.
+const lxcDefaultShutdownTimeout = "60s"
+
+// lxcShutdownTimeout returns the shutdown_timeout of a resource. It is
+// zero if the container is to be stopped right away.
+func lxcShutdownTimeout(d *schema.ResourceData) time.Duration {
+	timeout, _ := time.ParseDuration(d.Get("shutdown_timeout").(string))
+	return timeout
+}
+
+// lxcSetState moves the container into the given state, which is one of
+// running, stopped or frozen.
+func lxcSetState(c *lxc.Container, state string, shutdownTimeout time.Duration, deadline time.Time) error {
+	switch state {
+	case "running":
+		switch c.State() {
+		case lxc.FROZEN:
+			log.Printf("[INFO] Unfreezing container %s\n", c.Name())
+			if err := c.Unfreeze(); err != nil {
+				return fmt.Errorf("Unable to unfreeze container: %s", err)
+			}
+
+			return lxcWaitForState(c, lxc.RUNNING, lxcRemaining(deadline))
+		case lxc.STOPPED:
+			return lxcStart(c, deadline)
+		}
+	case "stopped":
+		return lxcStop(c, shutdownTimeout, deadline)
+	case "frozen":
+		if c.State() == lxc.STOPPED {
+			if err := lxcStart(c, deadline); err != nil {
 				return err
 			}
 		}
 
-		// now rewrite all custom config options
-		log.Printf("[DEBUG] %v", options)
-		if err := ioutil.WriteFile(customConfigFile, []byte(strings.Join(options, "\n")), 0640); err != nil {
+		if c.State() != lxc.FROZEN {
+			log.Printf("[INFO] Freezing container %s\n", c.Name())
+			if err := c.Freeze(); err != nil {
+				return fmt.Errorf("Unable to freeze container: %s", err)
+			}
+
+			return lxcWaitForState(c, lxc.FROZEN, lxcRemaining(deadline))
+		}
+	default:
+		return fmt.Errorf("Invalid state %s", state)
+	}
+
+	return nil
+}
+
+// lxcStableState returns the state of a container as one of the values
+// accepted by lxcSetState. A container that is changing its state is
+// waited for first.
+func lxcStableState(c *lxc.Container, deadline time.Time) (string, error) {
+	var err error
+	switch c.State() {
+	case lxc.STARTING:
+		err = lxcWaitForState(c, lxc.RUNNING, lxcRemaining(deadline))
+	case lxc.STOPPING, lxc.ABORTING:
+		err = lxcWaitForState(c, lxc.STOPPED, lxcRemaining(deadline))
+	case lxc.FREEZING:
+		err = lxcWaitForState(c, lxc.FROZEN, lxcRemaining(deadline))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch c.State() {
+	case lxc.RUNNING, lxc.THAWED:
+		return "running", nil
+	case lxc.STOPPED:
+		return "stopped", nil
+	case lxc.FROZEN:
+		return "frozen", nil
+	}
+
+	return "", fmt.Errorf("Container %s is in the unexpected state %s", c.Name(), c.State())
+}
+
+// lxcStart starts a stopped container with the current contents of its
+// config file.
+func lxcStart(c *lxc.Container, deadline time.Time) error {
+	if err := lxcReloadConfig(c); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Starting container %s\n", c.Name())
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("Unable to start container: %s", err)
+	}
+
+	return lxcWaitForState(c, lxc.RUNNING, lxcRemaining(deadline))
+}
+
+func lxcValidateState(v interface{}, k string) (ws []string, errors []error) {
+	switch v.(string) {
+	case "running", "stopped", "frozen":
+	default:
+		errors = append(errors, fmt.Errorf("%s must be one of running, stopped or frozen", k))
+	}
+
+	return
+}
+
+func lxcIsCgroupKey(key string) bool {
+	return strings.HasPrefix(key, "lxc.cgroup.") || strings.HasPrefix(key, "lxc.cgroup2.")
+}
+
+// lxcReadConfig populates the backend, options and network interfaces of
+// a resource from the container's config and the custom config file
+// written by lxcOptions.
+func lxcReadConfig(c *lxc.Container, d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	configFile := config.LXCPath + "/" + c.Name() + "/config"
+	customConfigFile := config.LXCPath + "/" + c.Name() + "/config_tf"
+
+	// the actual backend chosen for "best" is not reported
+	if backend := lxcReadBackend(c); backend != "" && d.Get("backend").(string) != "best" {
+		d.Set("backend", backend)
+	}
+
+	options := make(map[string]interface{})
+	var networkInterfaces []interface{}
+	var nic map[string]interface{}
+	var mounts []interface{}
+
+	mainConfig, err := lxcconfig.ParseFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	// if the custom config file is no longer included, none of its
+	// settings are in effect.
+	custom := lxcconfig.New()
+	if mainConfig.HasEntry("lxc.include", customConfigFile) {
+		custom, err = lxcconfig.ParseFile(customConfigFile)
+		if os.IsNotExist(err) {
+			custom = lxcconfig.New()
+		} else if err != nil {
+			return err
+		}
+	}
+
+	offset, err := lxcNetworkOffset(c)
+	if err != nil {
+		return err
+	}
+
+	sections := lxcConfigSections(custom)
+
+	currentOptions := d.Get("options").(map[string]interface{})
+	currentInterfaces := d.Get("network_interface").([]interface{})
+	mountOption, _ := currentOptions["lxc.mount.entry"].(string)
+
+	// options are written with their current key names, so they are
+	// mapped back to the names used in the configuration.
+	optionNames := make(map[string]string)
+	for k := range currentOptions {
+		if config.LegacyConfig {
+			optionNames[k] = k
+		} else {
+			optionNames[lxcTranslateKey(k, offset+len(currentInterfaces)-1)] = k
+		}
+	}
+
+	newInterface := func(i int) map[string]interface{} {
+		nic := map[string]interface{}{
+			"type":       "",
+			"name":       "",
+			"management": false,
+			"options":    make(map[string]interface{}),
+		}
+		// management is not stored in the config file
+		if i < len(currentInterfaces) {
+			nic["management"] = currentInterfaces[i].(map[string]interface{})["management"]
+		}
+		return nic
+	}
+
+	networkOptionName := func(i int, key string) string {
+		if i < len(currentInterfaces) {
+			for k := range currentInterfaces[i].(map[string]interface{})["options"].(map[string]interface{}) {
+				if lxcTranslateNetworkKey(k) == key {
+					return k
+				}
+			}
+		}
+		return key
+	}
+
+	optionKeys := make(map[string]string)
+	for _, entry := range sections[""].Entries() {
+		k := entry.Key
+		v := entry.Value
+
+		if name, ok := optionNames[k]; ok && (k != "lxc.mount.entry" || v == mountOption) {
+			options[name] = v
+			optionKeys[name] = k
+			continue
+		}
+
+		if index, key, ok := lxcParseNetworkKey(k); ok && !config.LegacyConfig && index >= offset {
+			i := index - offset
+			for len(networkInterfaces) <= i {
+				networkInterfaces = append(networkInterfaces, newInterface(len(networkInterfaces)))
+			}
+
+			nic := networkInterfaces[i].(map[string]interface{})
+			switch key {
+			case "type":
+				nic["type"] = v
+			case "name":
+				nic["name"] = v
+			default:
+				nic["options"].(map[string]interface{})[networkOptionName(i, key)] = v
+			}
+			continue
+		}
+
+		switch {
+		case k == "lxc.network.type":
+			nic = newInterface(len(networkInterfaces))
+			nic["type"] = v
+			networkInterfaces = append(networkInterfaces, nic)
+		case k == "lxc.network.name" && nic != nil:
+			nic["name"] = v
+		case strings.HasPrefix(k, "lxc.network.") && nic != nil:
+			nic["options"].(map[string]interface{})[strings.TrimPrefix(k, "lxc.network.")] = v
+		case k == "lxc.mount.entry":
+			mount, err := lxcParseMountEntry(v)
+			if err != nil {
+				return err
+			}
+			mounts = append(mounts, mount)
+		default:
+			options[k] = v
+			optionKeys[k] = k
+		}
+	}
+
+	// an option may have been overridden in the container's own config.
+	// liblxc merges the values of list keys from all config files, so
+	// only the container's own config is looked at.
+	overrides := lxcConfigOverrides(mainConfig, customConfigFile)
+	for name, k := range optionKeys {
+		if v, ok := overrides[k]; ok {
+			options[name] = v
+		}
+	}
+
+	if err := d.Set("options", options); err != nil {
+		return err
+	}
+
+	for i, n := range networkInterfaces {
+		lxcReadNetworkInterface(c, n.(map[string]interface{}), offset+i, config.LegacyConfig, d.Get("include_link_local").(bool))
+	}
+
+	if err := d.Set("network_interface", networkInterfaces); err != nil {
+		return err
+	}
+
+	if err := d.Set("mount", mounts); err != nil {
+		return err
+	}
+
+	limits, err := lxcReadLimits(sections[lxcLimitsSection], d.Get("limits").([]interface{}))
+	if err != nil {
+		return err
+	}
+
+	if err := d.Set("limits", limits); err != nil {
+		return err
+	}
+
+	autostartBlock, err := lxcReadAutostart(sections[lxcAutostartSection])
+	if err != nil {
+		return err
+	}
+
+	if err := d.Set("autostart", autostartBlock); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// lxcConfigOverrides returns the keys that the container's own config sets
+// after including the custom config file, and so take precedence over it.
+// Keys that liblxc adds to instead of replacing are left out.
+func lxcConfigOverrides(mainConfig *lxcconfig.File, customConfigFile string) map[string]string {
+	overrides := make(map[string]string)
+	included := false
+	for _, entry := range mainConfig.Entries() {
+		if entry.Key == "lxc.include" {
+			included = included || entry.Value == customConfigFile
+			continue
+		}
+
+		if included && !lxcIsListKey(entry.Key) {
+			overrides[entry.Key] = entry.Value
+		}
+	}
+
+	return overrides
+}
+
+// lxcIsListKey reports whether liblxc adds every value of a config key to
+// a list, rather than replacing the previous value.
+func lxcIsListKey(k string) bool {
+	switch k {
+	case "lxc.cap.drop", "lxc.cap.keep", "lxc.mount.auto", "lxc.mount.entry",
+		"lxc.group", "lxc.environment", "lxc.idmap", "lxc.id_map", "lxc.apparmor.raw":
+		return true
+	}
+
+	if strings.HasPrefix(k, "lxc.hook.") {
+		return true
+	}
+
+	for _, suffix := range []string{".ipv4", ".ipv6", ".ipv4.address", ".ipv6.address"} {
+		if strings.HasPrefix(k, "lxc.net") && strings.HasSuffix(k, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lxcRefreshContainer reads a container resource and removes it from the
+// state if the container no longer exists.
+func lxcRefreshContainer(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	handles := newLXCHandles(config)
+	defer handles.release()
+
+	c, err := handles.get(d.Id())
+	if err != nil {
+		return err
+	}
+
+	if !c.Defined() {
+		log.Printf("[WARN] Container %s not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	return lxcReadContainer(c, d, config)
+}
+
+// lxcUpdateContainer applies the changes to a container resource that do
+// not require a new container.
+func lxcUpdateContainer(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	deadline := time.Now().Add(d.Timeout(schema.TimeoutUpdate))
+	handles := newLXCHandles(config)
+	defer handles.release()
+
+	c, err := handles.get(d.Id())
+	if err != nil {
+		return err
+	}
+
+	// a container that is going to be stopped is stopped first so
+	// that config changes do not cause a needless restart.
+	state := d.Get("state").(string)
+	if d.HasChange("state") && state == "stopped" {
+		if err := lxcSetState(c, state, lxcShutdownTimeout(d), deadline); err != nil {
+			return err
+		}
+	}
+
+	// a renamed container is stopped for the rename and put back into
+	// its previous state once the config changes are applied.
+	var restore string
+	if d.HasChange("name") {
+		// a container that is still starting or stopping is waited for,
+		// so that the state it settles in is the one restored.
+		if restore, err = lxcStableState(c, deadline); err != nil {
+			return err
+		}
+		if c, err = lxcRename(handles, c, d, config, deadline); err != nil {
+			return err
+		}
+	}
+
+	if d.HasChange("options") || d.HasChange("network_interface") ||
+		d.HasChange("limits") || d.HasChange("mount") || d.HasChange("autostart") {
+		if err := lxcUpdateConfig(c, d, config, deadline); err != nil {
+			return err
+		}
+	}
+
+	if d.HasChange("state") {
+		restore = state
+	}
+
+	if restore != "" {
+		if err := lxcSetState(c, restore, lxcShutdownTimeout(d), deadline); err != nil {
+			return err
+		}
+	}
+
+	return lxcReadContainer(c, d, config)
+}
+
+// lxcDeleteContainer stops and destroys the container of a resource.
+func lxcDeleteContainer(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	deadline := time.Now().Add(d.Timeout(schema.TimeoutDelete))
+	handles := newLXCHandles(config)
+	defer handles.release()
+
+	c, err := handles.get(d.Id())
+	if err != nil {
+		return err
+	}
+
+	if !c.Defined() {
+		log.Printf("[INFO] Container %s already deleted", d.Id())
+		return nil
+	}
+
+	if err := lxcStop(c, lxcShutdownTimeout(d), deadline); err != nil {
+		return err
+	}
+
+	if err := c.Destroy(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// lxcReadContainer refreshes the attributes of an existing container.
+func lxcReadContainer(c *lxc.Container, d *schema.ResourceData, meta interface{}) error {
+	if err := lxcReadConfig(c, d, meta); err != nil {
+		return err
+	}
+
+	d.Set("name", c.Name())
+	d.Set("state", strings.ToLower(c.State().String()))
+
+	if err := lxcIPAddressConfiguration(c, d); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// lxcReadBackend returns the name of the storage backend of the container
+// as it is used in the backend attribute.
+func lxcReadBackend(c *lxc.Container) string {
+	var rootfs string
+	for _, key := range []string{"lxc.rootfs.backend", "lxc.rootfs.path", "lxc.rootfs"} {
+		if v := c.ConfigItem(key); len(v) > 0 && v[0] != "" {
+			rootfs = v[0]
+			break
+		}
+	}
+
+	if rootfs == "" {
+		return ""
+	}
+
+	if strings.HasPrefix(rootfs, "/") {
+		return "directory"
+	}
+
+	switch strings.SplitN(rootfs, ":", 2)[0] {
+	case "dir":
+		return "directory"
+	case "btrfs":
+		return "btrfs"
+	case "lvm":
+		return "lvm"
+	case "zfs":
+		return "zfs"
+	case "aufs":
+		return "aufs"
+	case "overlay", "overlayfs":
+		return "overlayfs"
+	case "loop":
+		return "loopback"
+	}
+
+	return ""
+}
+
+// lxcImportDefaults sets every attribute that has a default to it. Read
+// overwrites the attributes it can discover. The others, such as template
+// parameters or shutdown_timeout, would otherwise show a diff on the next
+// plan, or replace the container.
+func lxcImportDefaults(d *schema.ResourceData, s map[string]*schema.Schema) error {
+	for k, v := range s {
+		if v.Default == nil {
+			continue
+		}
+
+		if err := d.Set(k, v.Default); err != nil {
 			return err
 		}
 	}
@@ -123,23 +1024,29 @@ func lxcCheckBackend(backend string) (lxc.BackendStore, error) {
 }
 
 func lxcIPAddressConfiguration(c *lxc.Container, d *schema.ResourceData) error {
+	offset, err := lxcNetworkOffset(c)
+	if err != nil {
+		return err
+	}
+
 	// Loop through all interfaces and see if one is marked as management
 	managementNIC := "eth0"
-	i := 0
 	networkInterfaces := d.Get("network_interface").([]interface{})
-	for _, n := range networkInterfaces {
+	for i, n := range networkInterfaces {
 		nic := n.(map[string]interface{})
-		if nic["management"] == true {
-			managementNIC = fmt.Sprintf("%s%s", "eth", strconv.Itoa(i))
+		if nic["management"].(bool) {
+			managementNIC = lxcNetworkInterfaceName(nic, offset+i)
 		}
-		i++
 	}
 
+	includeLinkLocal := d.Get("include_link_local").(bool)
+
 	// Get the IP addresses of the management NIC
 	// For now, we'll just use the first returned IP.
 	d.Set("address_v4", "")
 	ipv4s, err := c.IPv4Address(managementNIC)
 	if err == nil {
+		ipv4s = lxcFilterAddresses(ipv4s, includeLinkLocal)
 		if len(ipv4s) > 0 {
 			d.Set("address_v4", ipv4s[0])
 			d.SetConnInfo(map[string]string{
@@ -152,10 +1059,11 @@ func lxcIPAddressConfiguration(c *lxc.Container, d *schema.ResourceData) error {
 	d.Set("address_v6", "")
 	ipv6s, err := c.IPv6Address(managementNIC)
 	if err == nil {
+		ipv6s = lxcFilterAddresses(ipv6s, includeLinkLocal)
 		if len(ipv6s) > 0 {
 			d.Set("address_v6", ipv6s[0])
 		}
 	}
 
-	return nil
+	return lxcReadAddresses(c, d)
 }
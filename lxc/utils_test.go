@@ -0,0 +1,35 @@
+package lxc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jtopjian/terraform-provider-lxc/lxcconfig"
+)
+
+func TestLXCConfigOverrides(t *testing.T) {
+	f, err := lxcconfig.Parse(strings.NewReader(`lxc.include = /usr/share/lxc/config/ubuntu.common.conf
+lxc.arch = amd64
+lxc.include = /var/lib/lxc/web/config_tf
+lxc.arch = i686
+lxc.cap.drop = sys_admin
+lxc.mount.auto = proc sys
+lxc.net.0.ipv4.address = 10.0.0.2/24
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"lxc.arch": "i686",
+	}
+
+	if actual := lxcConfigOverrides(f, "/var/lib/lxc/web/config_tf"); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	if actual := lxcConfigOverrides(f, "/var/lib/lxc/db/config_tf"); len(actual) != 0 {
+		t.Fatalf("Expected no overrides without the include, got %#v", actual)
+	}
+}
@@ -0,0 +1,338 @@
+// Package lxcconfig parses and writes LXC container config files.
+//
+// Files are kept line by line, so comments, blank lines and the order of
+// repeated keys survive a round trip. Only lines that are changed are
+// rewritten.
+package lxcconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Line is a single line of a config file. Blank lines and comments have
+// an empty Key.
+type Line struct {
+	Key   string
+	Value string
+
+	raw string
+}
+
+// String returns the line as it is written to the file.
+func (l *Line) String() string {
+	if l.raw != "" || l.Key == "" {
+		return l.raw
+	}
+
+	return fmt.Sprintf("%s = %s", l.Key, l.Value)
+}
+
+// IsComment reports whether the line is a comment.
+func (l *Line) IsComment() bool {
+	return l.Key == "" && strings.HasPrefix(strings.TrimSpace(l.raw), "#")
+}
+
+// Network is a network interface section of a config file. Keys are
+// relative to the interface, for example "link" or "ipv4.address".
+type Network struct {
+	Index   int
+	Legacy  bool
+	Entries []*Line
+}
+
+// Get returns the last value of a key of the interface.
+func (n *Network) Get(key string) string {
+	var value string
+	for _, l := range n.Entries {
+		if l.Key == key {
+			value = l.Value
+		}
+	}
+
+	return value
+}
+
+// File is a parsed config file.
+type File struct {
+	Lines []*Line
+
+	// noNewline is set when the last line of a parsed file was not
+	// terminated, so that it is written back the same way.
+	noNewline bool
+}
+
+// New returns an empty config file.
+func New() *File {
+	return &File{}
+}
+
+// Parse reads a config file.
+func Parse(r io.Reader) (*File, error) {
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	f := New()
+	if len(contents) == 0 {
+		return f, nil
+	}
+
+	lines := strings.Split(string(contents), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		f.noNewline = true
+	}
+
+	for _, line := range lines {
+		f.Lines = append(f.Lines, parseLine(line))
+	}
+
+	return f, nil
+}
+
+// ParseFile reads the config file at the given path.
+func ParseFile(path string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+func parseLine(raw string) *Line {
+	line := strings.TrimSpace(raw)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return &Line{raw: raw}
+	}
+
+	kv := strings.SplitN(line, "=", 2)
+	if len(kv) != 2 {
+		// liblxc refuses such lines, but they are kept as they are
+		return &Line{raw: raw}
+	}
+
+	return &Line{
+		Key:   strings.TrimSpace(kv[0]),
+		Value: strings.TrimSpace(kv[1]),
+		raw:   raw,
+	}
+}
+
+// Bytes returns the contents of the file.
+func (f *File) Bytes() []byte {
+	var buf bytes.Buffer
+	for i, l := range f.Lines {
+		buf.WriteString(l.String())
+		if i < len(f.Lines)-1 || !f.noNewline {
+			buf.WriteString("\n")
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// WriteTo writes the contents of the file to w.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(f.Bytes())
+	return int64(n), err
+}
+
+// WriteFile writes the file to the given path.
+func (f *File) WriteFile(path string, perm os.FileMode) error {
+	return ioutil.WriteFile(path, f.Bytes(), perm)
+}
+
+// Entries returns all lines that hold a key, in order.
+func (f *File) Entries() []*Line {
+	var entries []*Line
+	for _, l := range f.Lines {
+		if l.Key != "" {
+			entries = append(entries, l)
+		}
+	}
+
+	return entries
+}
+
+// Get returns all values of a key, in order.
+func (f *File) Get(key string) []string {
+	var values []string
+	for _, l := range f.Lines {
+		if l.Key == key {
+			values = append(values, l.Value)
+		}
+	}
+
+	return values
+}
+
+// Has reports whether the file holds the given key.
+func (f *File) Has(key string) bool {
+	return len(f.Get(key)) > 0
+}
+
+// HasEntry reports whether the file holds the given key and value.
+func (f *File) HasEntry(key, value string) bool {
+	for _, l := range f.Lines {
+		if l.Key == key && l.Value == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Add appends a key and value to the file.
+func (f *File) Add(key, value string) {
+	f.Lines = append(f.Lines, &Line{Key: key, Value: value})
+	f.noNewline = false
+}
+
+// AddComment appends a comment to the file.
+func (f *File) AddComment(comment string) {
+	f.Lines = append(f.Lines, &Line{raw: "# " + comment})
+	f.noNewline = false
+}
+
+// Set replaces all values of a key with a single value. The value takes
+// the place of the first existing value, or is appended to the file.
+func (f *File) Set(key, value string) {
+	var lines []*Line
+	found := false
+	for _, l := range f.Lines {
+		if l.Key != key {
+			lines = append(lines, l)
+			continue
+		}
+
+		if !found {
+			found = true
+			if l.Value != value {
+				l = &Line{Key: key, Value: value}
+			}
+			lines = append(lines, l)
+		}
+	}
+
+	f.Lines = lines
+	if !found {
+		f.Add(key, value)
+	}
+}
+
+// Delete removes all values of a key.
+func (f *File) Delete(key string) {
+	f.filter(func(l *Line) bool {
+		return l.Key == key
+	})
+}
+
+// DeleteEntry removes all lines with the given key and value.
+func (f *File) DeleteEntry(key, value string) {
+	f.filter(func(l *Line) bool {
+		return l.Key == key && l.Value == value
+	})
+}
+
+func (f *File) filter(remove func(*Line) bool) {
+	var lines []*Line
+	for _, l := range f.Lines {
+		if !remove(l) {
+			lines = append(lines, l)
+		}
+	}
+
+	f.Lines = lines
+}
+
+// Includes returns the files included with lxc.include.
+func (f *File) Includes() []string {
+	return f.Get("lxc.include")
+}
+
+// Networks returns the network interfaces defined in the file, ordered by
+// their index. A legacy lxc.network.type key starts a new interface and
+// the following unindexed lxc.network.* keys belong to it. Indexed keys
+// of the form lxc.net.<index>.* and lxc.network.<index>.* belong to the
+// interface with that index.
+func (f *File) Networks() []*Network {
+	networks := make(map[int]*Network)
+	var current *Network
+	legacyIndex := 0
+
+	get := func(index int, legacy bool) *Network {
+		if n, ok := networks[index]; ok {
+			return n
+		}
+		n := &Network{Index: index, Legacy: legacy}
+		networks[index] = n
+		return n
+	}
+
+	for _, l := range f.Entries() {
+		if index, key, ok := splitIndexedKey(l.Key, "lxc.net."); ok {
+			n := get(index, false)
+			n.Entries = append(n.Entries, &Line{Key: key, Value: l.Value})
+			continue
+		}
+
+		if index, key, ok := splitIndexedKey(l.Key, "lxc.network."); ok {
+			n := get(index, true)
+			n.Entries = append(n.Entries, &Line{Key: key, Value: l.Value})
+			continue
+		}
+
+		switch {
+		case l.Key == "lxc.network.type":
+			current = get(legacyIndex, true)
+			legacyIndex++
+			current.Entries = append(current.Entries, &Line{Key: "type", Value: l.Value})
+		case strings.HasPrefix(l.Key, "lxc.network.") && current != nil:
+			current.Entries = append(current.Entries, &Line{Key: strings.TrimPrefix(l.Key, "lxc.network."), Value: l.Value})
+		}
+	}
+
+	var indexes []int
+	for index := range networks {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	var result []*Network
+	for _, index := range indexes {
+		result = append(result, networks[index])
+	}
+
+	return result
+}
+
+// splitIndexedKey splits a key such as lxc.net.0.link into its index and
+// the key of the interface.
+func splitIndexedKey(key, prefix string) (int, string, bool) {
+	if !strings.HasPrefix(key, prefix) {
+		return 0, "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(key, prefix), ".", 2)
+	if len(parts) != 2 {
+		return 0, "", false
+	}
+
+	index, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", false
+	}
+
+	return index, parts[1], true
+}
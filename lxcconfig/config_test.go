@@ -0,0 +1,129 @@
+package lxcconfig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testConfig = `# Template used to create this container
+lxc.include = /usr/share/lxc/config/ubuntu.common.conf
+
+lxc.rootfs.path = dir:/var/lib/lxc/test/rootfs
+lxc.uts.name=test
+  lxc.mount.entry = proc proc proc nodev 0 0
+lxc.mount.entry = /srv srv none bind 0 0
+
+lxc.net.0.type = veth
+lxc.net.0.link = lxcbr0
+lxc.net.2.type = empty
+`
+
+func TestParseRoundTrip(t *testing.T) {
+	for _, contents := range []string{testConfig, "", "lxc.uts.name = test", "lxc.uts.name = test\r\n\r\n"} {
+		f, err := Parse(strings.NewReader(contents))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := string(f.Bytes()); actual != contents {
+			t.Fatalf("Expected:\n%q\nGot:\n%q", contents, actual)
+		}
+	}
+}
+
+func TestParseEntries(t *testing.T) {
+	f, err := Parse(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := f.Get("lxc.uts.name"); !reflect.DeepEqual(actual, []string{"test"}) {
+		t.Fatalf("Unexpected lxc.uts.name: %v", actual)
+	}
+
+	expected := []string{"proc proc proc nodev 0 0", "/srv srv none bind 0 0"}
+	if actual := f.Get("lxc.mount.entry"); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("Unexpected lxc.mount.entry: %v", actual)
+	}
+
+	if actual := f.Includes(); !reflect.DeepEqual(actual, []string{"/usr/share/lxc/config/ubuntu.common.conf"}) {
+		t.Fatalf("Unexpected includes: %v", actual)
+	}
+
+	if actual := len(f.Entries()); actual != 8 {
+		t.Fatalf("Expected 8 entries, got %d", actual)
+	}
+}
+
+func TestFileModify(t *testing.T) {
+	f, err := Parse(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Set("lxc.mount.entry", "/data data none bind 0 0")
+	f.Set("lxc.rootfs.path", "dir:/var/lib/lxc/test/rootfs")
+	f.DeleteEntry("lxc.net.2.type", "empty")
+	f.Delete("lxc.net.0.link")
+	f.Add("lxc.include", "/var/lib/lxc/test/config_tf")
+
+	expected := `# Template used to create this container
+lxc.include = /usr/share/lxc/config/ubuntu.common.conf
+
+lxc.rootfs.path = dir:/var/lib/lxc/test/rootfs
+lxc.uts.name=test
+lxc.mount.entry = /data data none bind 0 0
+
+lxc.net.0.type = veth
+lxc.include = /var/lib/lxc/test/config_tf
+`
+
+	if actual := string(f.Bytes()); actual != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, actual)
+	}
+
+	if f.Has("lxc.net.0.link") || !f.HasEntry("lxc.net.0.type", "veth") {
+		t.Fatalf("Unexpected network entries:\n%s", f.Bytes())
+	}
+}
+
+func TestFileNetworks(t *testing.T) {
+	f, err := Parse(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	networks := f.Networks()
+	if len(networks) != 2 {
+		t.Fatalf("Expected 2 networks, got %d", len(networks))
+	}
+
+	if networks[0].Index != 0 || networks[0].Get("link") != "lxcbr0" || networks[0].Legacy {
+		t.Fatalf("Unexpected first network: %#v", networks[0])
+	}
+
+	if networks[1].Index != 2 || networks[1].Get("type") != "empty" {
+		t.Fatalf("Unexpected second network: %#v", networks[1])
+	}
+
+	legacy := `lxc.network.type = veth
+lxc.network.link = lxcbr0
+lxc.network.ipv4 = 10.0.0.2/24
+lxc.network.type = empty
+`
+
+	f, err = Parse(strings.NewReader(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	networks = f.Networks()
+	if len(networks) != 2 {
+		t.Fatalf("Expected 2 networks, got %d", len(networks))
+	}
+
+	if !networks[0].Legacy || networks[0].Get("ipv4") != "10.0.0.2/24" || networks[1].Get("type") != "empty" {
+		t.Fatalf("Unexpected networks: %#v %#v", networks[0], networks[1])
+	}
+}